@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/importer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// runImport handles the import subcommand: import -user EMAIL [flags] FILE
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	email := fs.String("user", "", "email of the user to import the sessions for")
+	spec := fs.String("map", "", "column mapping as field=column pairs, e.g. date=Day,errors=Unforced errors")
+	dateFormat := fs.String("date-format", importer.DefaultDateFormat, "Go time layout of the date column")
+	dryRun := fs.Bool("dry-run", false, "validate the file and show what would be imported without storing anything")
+
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if *email == "" || fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	mapping := importer.DefaultMapping()
+	if err := mapping.Parse(*spec); err != nil {
+		log.Fatalf("Invalid -map: %v", err)
+	}
+	mapping.DateFormat = *dateFormat
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	sessions, lineErrors, err := importer.Read(file, mapping)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", fs.Arg(0), err)
+	}
+	if len(lineErrors) > 0 {
+		for _, lineErr := range lineErrors {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), lineErr)
+		}
+		log.Fatalf("%d invalid rows, nothing was imported", len(lineErrors))
+	}
+
+	entries, total := importer.Totals(sessions)
+
+	if *dryRun {
+		for _, session := range sessions {
+			fmt.Printf("%s  %-30s  %-20s  %d errors in %d entries\n",
+				session.SessionDate.Format("2006-01-02"), session.Name, session.OpponentName,
+				session.ErrorCount, len(session.Errors))
+		}
+		fmt.Printf("Dry run: would import %d sessions with %d errors in %d entries\n", len(sessions), total, entries)
+		return
+	}
+
+	// Interrupting the command rolls the import back
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.New(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	users := &models.UserService{DB: db}
+	user, err := users.GetByEmail(ctx, models.NormalizeEmail(*email))
+	if err != nil {
+		log.Fatalf("Failed to find user %s: %v", *email, err)
+	}
+
+	sessionService := &models.SessionService{DB: db}
+	if err := sessionService.Import(ctx, user.ID, sessions); err != nil {
+		log.Fatalf("Import failed, nothing was imported: %v", err)
+	}
+
+	fmt.Printf("Imported %d sessions with %d errors in %d entries\n", len(sessions), total, entries)
+}
@@ -2,42 +2,106 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/jimsyyap/tennis-tracker/backend/internal/api"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
 	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
-	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/memory"
 )
 
+const usage = `Usage:
+  server [serve] [flags]                 run the API server
+  server migrate up [flags]              apply all pending migrations
+  server migrate down [flags]            roll back all migrations
+  server migrate to VERSION [flags]      migrate up or down to VERSION
+  server migrate status [flags]          show the current migration version
+  server migrate force VERSION [flags]   set the version after a failed migration
+  server import -user EMAIL [flags] FILE import sessions and error counts from CSV
+
+Run "server serve -h" to list the configuration flags, and "server import -h"
+for the column mapping and dry-run flags.
+`
+
 func main() {
-	// Run database migrations
-	if err := database.MigrateUp(); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	args := os.Args[1:]
+
+	// Default to serve so a bare binary keeps starting the server
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "migrate":
+		runMigrate(args)
+	case "import":
+		runImport(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
+}
 
-	// Initialize database connection
-	db, err := database.New()
+// serve runs the API server until it receives SIGINT or SIGTERM
+func serve(args []string) {
+	// Load and validate configuration
+	cfg, err := config.Load(args)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	defer db.Close()
 
-	log.Println("Connected to database successfully")
+	mail := mailer.New(cfg.SMTP)
 
-	// Initialize router and API handlers
-	router := api.NewRouter(db)
+	// Initialize the API handlers on the configured store
+	var handler *api.Handler
+	if cfg.Store == config.StoreMemory {
+		handler = api.NewMemoryHandler(cfg, memory.New(), mail)
+		log.Println("Keeping data in memory; it is lost when the server stops")
+	} else {
+		// Run database migrations
+		if cfg.AutoMigrate {
+			if err := database.MigrateUp(cfg.DatabaseURL); err != nil {
+				log.Fatalf("Failed to migrate database: %v", err)
+			}
+		}
 
-	// Configure the HTTP server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+		// Initialize database connection
+		db, err := database.New(cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+
+		log.Println("Connected to database successfully")
+
+		handler = api.NewHandler(cfg, db, mail)
 	}
 
+	// Clean up rate limits in the background until shutdown
+	sweepCtx, stopSweeping := context.WithCancel(context.Background())
+	defer stopSweeping()
+	go handler.SweepRateLimits(sweepCtx)
+
+	// Initialize router
+	router := api.NewRouter(cfg, handler)
+
+	// Configure the HTTP server
+	port := strconv.Itoa(cfg.Port)
+
 	srv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      router,
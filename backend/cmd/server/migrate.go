@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// runMigrate handles the migrate subcommand: migrate ACTION [VERSION] [flags]
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	action, args := args[0], args[1:]
+
+	// to and force take a version before the flags
+	var version int
+	if action == "to" || action == "force" {
+		if len(args) == 0 {
+			log.Fatalf("migrate %s requires a version", action)
+		}
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < -1 || (action == "to" && v < 0) {
+			log.Fatalf("Invalid migration version %q", args[0])
+		}
+		version, args = v, args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	switch action {
+	case "up":
+		err = database.MigrateUp(cfg.DatabaseURL)
+	case "down":
+		err = database.MigrateDown(cfg.DatabaseURL)
+	case "to":
+		err = database.MigrateTo(cfg.DatabaseURL, uint(version))
+	case "force":
+		err = database.MigrateForce(cfg.DatabaseURL, version)
+	case "status":
+		err = printMigrationStatus(cfg.DatabaseURL)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate action %q\n\n%s", action, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// printMigrationStatus prints the current and latest migration versions
+func printMigrationStatus(dbURL string) error {
+	status, err := database.GetMigrationStatus(dbURL)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status.Dirty:
+		fmt.Printf("Version %d is dirty: a migration failed, fix it and run \"migrate force VERSION\"\n", status.Version)
+	case status.Version == status.Latest:
+		fmt.Printf("Version %d, up to date\n", status.Version)
+	default:
+		fmt.Printf("Version %d, latest is %d\n", status.Version, status.Latest)
+	}
+
+	return nil
+}
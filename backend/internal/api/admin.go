@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// UpdateRoleRequest represents the change role request body
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateUserRole lets an admin make another user a player or a coach
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := URLParamInt(r, "id")
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid user ID"))
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if !models.IsAssignableRole(req.Role) {
+		RespondWithError(w, r, apperr.Invalid("role", "Role must be player or coach"))
+		return
+	}
+
+	user, err := h.Users.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "User not found", "Failed to retrieve user")
+		return
+	}
+
+	// Admins are appointed in the database, so they cannot be demoted here
+	if user.Role == models.RoleAdmin {
+		RespondWithError(w, r, apperr.Forbidden("The role of an admin cannot be changed"))
+		return
+	}
+
+	user.Role = req.Role
+	if err := h.Users.Update(r.Context(), user); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to update user")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+// requireAdmin answers 403 unless the authenticated user is an admin
+func (h *Handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := middleware.GetUserID(r)
+		if err != nil {
+			RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+			return
+		}
+
+		user, err := h.Users.GetByID(r.Context(), userID)
+		if err != nil {
+			RespondWithLookupError(w, r, err, "User not found", "Failed to retrieve user")
+			return
+		}
+		if user.Role != models.RoleAdmin {
+			RespondWithError(w, r, apperr.Forbidden("Only admins can do that"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,546 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/memory"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// testServer is the API on the in-memory stores
+type testServer struct {
+	t       *testing.T
+	handler *Handler
+	router  http.Handler
+}
+
+// newTestServer starts the API on empty in-memory stores
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Store = config.StoreMemory
+	h := NewMemoryHandler(cfg, memory.New(), mailer.NewLogMailer(io.Discard))
+	return &testServer{t: t, handler: h, router: NewRouter(cfg, h)}
+}
+
+// do sends a request with an optional JSON body and bearer token, and
+// decodes the JSON response into out unless it is nil
+func (s *testServer) do(method, path, token string, body, out interface{}) int {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode response %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// register creates an account and returns its access token
+func (s *testServer) register(email string) string {
+	s.t.Helper()
+
+	var resp AuthResponse
+	status := s.do(http.MethodPost, "/api/register", "", RegisterRequest{
+		Name:     "Test Player",
+		Email:    email,
+		Password: "password123",
+	}, &resp)
+	if status != http.StatusCreated {
+		s.t.Fatalf("register %s: status %d", email, status)
+	}
+	return resp.Token
+}
+
+// createSession creates a session for the token's user and returns it
+func (s *testServer) createSession(token string) models.Session {
+	s.t.Helper()
+
+	var session models.Session
+	status := s.do(http.MethodPost, "/api/sessions", token, SessionRequest{
+		Name:         "Practice",
+		OpponentName: "Wall",
+		SessionDate:  time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	}, &session)
+	if status != http.StatusCreated {
+		s.t.Fatalf("create session: status %d", status)
+	}
+	return session
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.register("player@example.com")
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     int
+	}{
+		{name: "valid credentials", email: "player@example.com", password: "password123", want: http.StatusOK},
+		{name: "email in another case", email: "Player@Example.com", password: "password123", want: http.StatusOK},
+		{name: "wrong password", email: "player@example.com", password: "password124", want: http.StatusUnauthorized},
+		{name: "unknown email", email: "nobody@example.com", password: "password123", want: http.StatusUnauthorized},
+		{name: "missing password", email: "player@example.com", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp AuthResponse
+			status := s.do(http.MethodPost, "/api/login", "", LoginRequest{Email: tt.email, Password: tt.password}, &resp)
+			if status != tt.want {
+				t.Fatalf("login: status %d, want %d", status, tt.want)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			var user models.User
+			if status := s.do(http.MethodGet, "/api/user", resp.Token, nil, &user); status != http.StatusOK {
+				t.Fatalf("get user: status %d", status)
+			}
+			if user.Email != "player@example.com" {
+				t.Errorf("user email = %q, want player@example.com", user.Email)
+			}
+		})
+	}
+
+	if status := s.do(http.MethodPost, "/api/register", "", RegisterRequest{
+		Name:     "Someone Else",
+		Email:    "PLAYER@example.com",
+		Password: "password123",
+	}, nil); status != http.StatusConflict {
+		t.Errorf("register a taken email: status %d, want %d", status, http.StatusConflict)
+	}
+}
+
+func TestOtherUsersSessionNotFound(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register("owner@example.com")
+	other := s.register("other@example.com")
+	session := s.createSession(owner)
+	path := "/api/sessions/" + strconv.Itoa(session.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{name: "get", method: http.MethodGet, path: path},
+		{name: "update", method: http.MethodPut, path: path, body: SessionRequest{Name: "Mine now", SessionDate: time.Now()}},
+		{name: "delete", method: http.MethodDelete, path: path},
+		{name: "list errors", method: http.MethodGet, path: path + "/errors"},
+		{name: "log error", method: http.MethodPost, path: path + "/errors", body: ErrorRequest{Count: 1}},
+		{name: "share", method: http.MethodPost, path: path + "/share", body: ShareRequest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := s.do(tt.method, tt.path, other, tt.body, nil); status != http.StatusNotFound {
+				t.Errorf("status %d, want %d", status, http.StatusNotFound)
+			}
+		})
+	}
+
+	// The session is untouched
+	var got models.Session
+	if status := s.do(http.MethodGet, path, owner, nil, &got); status != http.StatusOK {
+		t.Fatalf("owner get: status %d", status)
+	}
+	if got.Name != session.Name || got.ErrorCount != 0 {
+		t.Errorf("session = %q with %d errors, want it unchanged", got.Name, got.ErrorCount)
+	}
+}
+
+func TestErrorCRUD(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("player@example.com")
+	session := s.createSession(token)
+	errorsPath := "/api/sessions/" + strconv.Itoa(session.ID) + "/errors"
+
+	// Create
+	var created models.ErrorEntry
+	status := s.do(http.MethodPost, errorsPath, token, ErrorRequest{Count: 2, Stroke: models.StrokeForehand, Outcome: models.OutcomeNet}, &created)
+	if status != http.StatusCreated {
+		t.Fatalf("log error: status %d", status)
+	}
+	if created.Side != models.SideForehand {
+		t.Errorf("side = %q, want it filled in from the stroke", created.Side)
+	}
+
+	// Invalid entries are refused with the field at fault
+	invalid := []struct {
+		req   ErrorRequest
+		field string
+	}{
+		{ErrorRequest{Count: 0}, "count"},
+		{ErrorRequest{Count: 1, Stroke: "lob"}, "stroke"},
+		{ErrorRequest{Count: 1, Stroke: models.StrokeServe, Side: models.SideBackhand}, "side"},
+		{ErrorRequest{Count: 1, Stroke: models.StrokeVolley, Outcome: models.OutcomeDoubleFault}, "outcome"},
+	}
+	for _, tt := range invalid {
+		var problem apperr.Problem
+		if status := s.do(http.MethodPost, errorsPath, token, tt.req, &problem); status != http.StatusBadRequest {
+			t.Errorf("log %+v: status %d, want %d", tt.req, status, http.StatusBadRequest)
+		}
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field {
+			t.Errorf("log %+v: errors %+v, want one on %s", tt.req, problem.Errors, tt.field)
+		}
+	}
+
+	// Read
+	var entries []models.ErrorEntry
+	if status := s.do(http.MethodGet, errorsPath, token, nil, &entries); status != http.StatusOK {
+		t.Fatalf("list errors: status %d", status)
+	}
+	if len(entries) != 1 || entries[0].ID != created.ID || entries[0].Count != 2 {
+		t.Fatalf("entries = %+v, want the created entry", entries)
+	}
+
+	// Update
+	entryPath := errorsPath + "/" + strconv.Itoa(created.ID)
+	var updated models.ErrorEntry
+	status = s.do(http.MethodPut, entryPath, token, ErrorRequest{Count: 5, Stroke: models.StrokeServe, Outcome: models.OutcomeDoubleFault}, &updated)
+	if status != http.StatusOK {
+		t.Fatalf("update error: status %d", status)
+	}
+	if updated.Count != 5 || updated.Stroke != models.StrokeServe || updated.Side != "" {
+		t.Errorf("updated = %+v, want 5 serve double faults", updated)
+	}
+
+	var got models.Session
+	if status := s.do(http.MethodGet, "/api/sessions/"+strconv.Itoa(session.ID), token, nil, &got); status != http.StatusOK {
+		t.Fatalf("get session: status %d", status)
+	}
+	if got.ErrorCount != 5 || got.ErrorsByStroke[models.StrokeServe] != 5 {
+		t.Errorf("session totals = %d, %v, want 5 serve errors", got.ErrorCount, got.ErrorsByStroke)
+	}
+
+	// Delete
+	if status := s.do(http.MethodDelete, entryPath, token, nil, nil); status != http.StatusOK {
+		t.Fatalf("delete error: status %d", status)
+	}
+	if status := s.do(http.MethodGet, errorsPath, token, nil, &entries); status != http.StatusOK || len(entries) != 0 {
+		t.Errorf("after delete: status %d, entries %+v, want none", status, entries)
+	}
+	if status := s.do(http.MethodDelete, entryPath, token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("delete again: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestExpiredShareLink(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("player@example.com")
+	session := s.createSession(token)
+
+	// A fresh link works
+	var active models.SharedLink
+	if status := s.do(http.MethodPost, "/api/sessions/"+strconv.Itoa(session.ID)+"/share", token, ShareRequest{}, &active); status != http.StatusCreated {
+		t.Fatalf("share: status %d", status)
+	}
+	var view map[string]interface{}
+	if status := s.do(http.MethodGet, "/api/shared/"+active.Token, "", nil, &view); status != http.StatusOK {
+		t.Fatalf("get active link: status %d", status)
+	}
+	if view["name"] != session.Name {
+		t.Errorf("shared name = %v, want %q", view["name"], session.Name)
+	}
+	for _, private := range []string{"user_id", "opponent_name", "opponent_id", "id"} {
+		if _, ok := view[private]; ok {
+			t.Errorf("shared view has %s, want it left out", private)
+		}
+	}
+
+	// The API only creates links of whole hours, so expire one through the store
+	expired := models.SharedLink{SessionID: session.ID}
+	if err := s.handler.Shares.Create(context.Background(), &expired, time.Millisecond); err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if status := s.do(http.MethodGet, "/api/shared/"+expired.Token, "", nil, nil); status != http.StatusGone {
+		t.Errorf("get expired link: status %d, want %d", status, http.StatusGone)
+	}
+	if status := s.do(http.MethodGet, "/api/shared/unknown", "", nil, nil); status != http.StatusNotFound {
+		t.Errorf("get unknown link: status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestLogoutEverywhere(t *testing.T) {
+	s := newTestServer(t)
+	first := s.register("player@example.com")
+
+	var other AuthResponse
+	login := LoginRequest{Email: "player@example.com", Password: "password123"}
+	if status := s.do(http.MethodPost, "/api/login", "", login, &other); status != http.StatusOK {
+		t.Fatalf("login: status %d", status)
+	}
+
+	// Logging out without a refresh token revokes every token issued so far
+	if status := s.do(http.MethodPost, "/api/logout", first, nil, nil); status != http.StatusOK {
+		t.Fatalf("logout: status %d", status)
+	}
+	for name, token := range map[string]string{"logged out token": first, "other token": other.Token} {
+		if status := s.do(http.MethodGet, "/api/user", token, nil, nil); status != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want %d", name, status, http.StatusUnauthorized)
+		}
+	}
+
+	// A token issued right after, typically within the same second, works
+	var fresh AuthResponse
+	if status := s.do(http.MethodPost, "/api/login", "", login, &fresh); status != http.StatusOK {
+		t.Fatalf("login again: status %d", status)
+	}
+	if status := s.do(http.MethodGet, "/api/user", fresh.Token, nil, nil); status != http.StatusOK {
+		t.Errorf("fresh token: status %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRoleChanges(t *testing.T) {
+	s := newTestServer(t)
+	player := s.register("player@example.com")
+	admin := s.register("admin@example.com")
+
+	// The profile update leaves the role alone
+	var user models.User
+	if status := s.do(http.MethodPut, "/api/user", player, map[string]string{"role": models.RoleCoach}, &user); status != http.StatusOK {
+		t.Fatalf("update user: status %d", status)
+	}
+	if user.Role != models.RolePlayer {
+		t.Errorf("role after profile update = %q, want %q", user.Role, models.RolePlayer)
+	}
+
+	rolePath := "/api/admin/users/" + strconv.Itoa(user.ID) + "/role"
+	coach := UpdateRoleRequest{Role: models.RoleCoach}
+	if status := s.do(http.MethodPut, rolePath, player, coach, nil); status != http.StatusForbidden {
+		t.Errorf("change role as a player: status %d, want %d", status, http.StatusForbidden)
+	}
+
+	// Admins are appointed through the store
+	adminUser, err := s.handler.Users.GetByEmail(context.Background(), "admin@example.com")
+	if err != nil {
+		t.Fatalf("get admin: %v", err)
+	}
+	adminUser.Role = models.RoleAdmin
+	if err := s.handler.Users.Update(context.Background(), adminUser); err != nil {
+		t.Fatalf("appoint admin: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		role string
+		want int
+	}{
+		{name: "make coach", path: rolePath, role: models.RoleCoach, want: http.StatusOK},
+		{name: "make admin", path: rolePath, role: models.RoleAdmin, want: http.StatusBadRequest},
+		{name: "demote admin", path: "/api/admin/users/" + strconv.Itoa(adminUser.ID) + "/role", role: models.RolePlayer, want: http.StatusForbidden},
+		{name: "unknown user", path: "/api/admin/users/999/role", role: models.RoleCoach, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := s.do(http.MethodPut, tt.path, admin, UpdateRoleRequest{Role: tt.role}, nil); status != tt.want {
+				t.Errorf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+
+	if status := s.do(http.MethodGet, "/api/user", player, nil, &user); status != http.StatusOK {
+		t.Fatalf("get user: status %d", status)
+	}
+	if user.Role != models.RoleCoach {
+		t.Errorf("role = %q, want %q", user.Role, models.RoleCoach)
+	}
+}
+
+func TestTypeFilterNeedsDatabase(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("player@example.com")
+	s.createSession(token)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/api/sessions", want: http.StatusOK},
+		{path: "/api/sessions?type=match", want: http.StatusNotImplemented},
+		{path: "/api/sessions?type=practice", want: http.StatusNotImplemented},
+		{path: "/api/stats?type=match", want: http.StatusNotImplemented},
+		{path: "/api/sessions?type=rally", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if status := s.do(http.MethodGet, tt.path, token, nil, nil); status != tt.want {
+				t.Errorf("status %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestInviteDoesNotRevealAccounts(t *testing.T) {
+	s := newTestServer(t)
+	coach := s.register("coach@example.com")
+	player := s.register("player@example.com")
+
+	// Coaches are appointed by admins
+	user, err := s.handler.Users.GetByEmail(context.Background(), "coach@example.com")
+	if err != nil {
+		t.Fatalf("get coach: %v", err)
+	}
+	user.Role = models.RoleCoach
+	if err := s.handler.Users.Update(context.Background(), user); err != nil {
+		t.Fatalf("make coach: %v", err)
+	}
+
+	// roster returns what the coach sees after inviting email
+	roster := func(email string) (int, string, []models.CoachLink) {
+		var resp SuccessResponse
+		status := s.do(http.MethodPost, "/api/coaching/players", coach, InviteRequest{Email: email}, &resp)
+
+		var links []models.CoachLink
+		if status := s.do(http.MethodGet, "/api/coaching/players", coach, nil, &links); status != http.StatusOK {
+			t.Fatalf("list players: status %d", status)
+		}
+		return status, resp.Message, links
+	}
+
+	missingStatus, missingMessage, missingLinks := roster("nobody@example.com")
+	existingStatus, existingMessage, existingLinks := roster("player@example.com")
+
+	if missingStatus != http.StatusAccepted || existingStatus != missingStatus || existingMessage != missingMessage {
+		t.Errorf("invite responses differ: %d %q for a missing account, %d %q for an existing one",
+			missingStatus, missingMessage, existingStatus, existingMessage)
+	}
+	if len(missingLinks) != 0 || len(existingLinks) != 0 {
+		t.Errorf("rosters = %+v and %+v, want both empty until the invitation is accepted", missingLinks, existingLinks)
+	}
+
+	// The invitee sees the invitation, and the coach sees the player once accepted
+	var invites []models.CoachLink
+	if status := s.do(http.MethodGet, "/api/coaching/coaches", player, nil, &invites); status != http.StatusOK || len(invites) != 1 {
+		t.Fatalf("list coaches: status %d, links %+v, want one invitation", status, invites)
+	}
+	path := "/api/coaching/" + strconv.Itoa(invites[0].ID)
+	if status := s.do(http.MethodPost, path+"/accept", coach, nil, nil); status != http.StatusNotFound {
+		t.Errorf("accept own invitation: status %d, want %d", status, http.StatusNotFound)
+	}
+	if status := s.do(http.MethodPost, path+"/accept", player, nil, nil); status != http.StatusOK {
+		t.Fatalf("accept: status %d", status)
+	}
+
+	var links []models.CoachLink
+	if status := s.do(http.MethodGet, "/api/coaching/players", coach, nil, &links); status != http.StatusOK {
+		t.Fatalf("list players: status %d", status)
+	}
+	if len(links) != 1 || links[0].PlayerEmail != "player@example.com" || links[0].Status != models.LinkAccepted {
+		t.Errorf("roster = %+v, want the accepted player", links)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	s := newTestServer(t)
+	token := s.register("player@example.com")
+
+	var formula models.Session
+	if status := s.do(http.MethodPost, "/api/sessions", token, SessionRequest{
+		Name:         `=HYPERLINK("http://example.com")`,
+		OpponentName: "@Wall",
+		SessionDate:  time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC),
+	}, &formula); status != http.StatusCreated {
+		t.Fatalf("create session: status %d", status)
+	}
+	withErrors := s.createSession(token)
+	for _, req := range []ErrorRequest{{Count: 2, Stroke: models.StrokeForehand}, {Count: 1}} {
+		if status := s.do(http.MethodPost, "/api/sessions/"+strconv.Itoa(withErrors.ID)+"/errors", token, req, nil); status != http.StatusCreated {
+			t.Fatalf("log error: status %d", status)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d", rec.Code)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if len(records) == 0 || !reflect.DeepEqual(records[0], exportCSVHeader) {
+		t.Fatalf("header = %v, want %v", records, exportCSVHeader)
+	}
+
+	column := make(map[string]int, len(exportCSVHeader))
+	for i, name := range exportCSVHeader {
+		column[name] = i
+	}
+	rows := make(map[string][][]string)
+	for _, record := range records[1:] {
+		id := record[column["session_id"]]
+		rows[id] = append(rows[id], record)
+	}
+
+	// A session without errors has exactly one row, with empty error columns
+	formulaRows := rows[strconv.Itoa(formula.ID)]
+	if len(formulaRows) != 1 {
+		t.Fatalf("session without errors has %d rows, want 1", len(formulaRows))
+	}
+	row := formulaRows[0]
+	for _, name := range exportCSVHeader[column["error_id"]:] {
+		if row[column[name]] != "" {
+			t.Errorf("%s = %q, want it empty", name, row[column[name]])
+		}
+	}
+
+	// Cells that spreadsheets would run as formulas are kept as text
+	if got, want := row[column["session_name"]], `'=HYPERLINK("http://example.com")`; got != want {
+		t.Errorf("session_name = %q, want %q", got, want)
+	}
+	if got, want := row[column["opponent_name"]], "'@Wall"; got != want {
+		t.Errorf("opponent_name = %q, want %q", got, want)
+	}
+
+	// A session with errors has one row per entry
+	if got := len(rows[strconv.Itoa(withErrors.ID)]); got != 2 {
+		t.Errorf("session with 2 entries has %d rows, want 2", got)
+	}
+	if len(rows) != 2 {
+		t.Errorf("export has %d sessions, want 2", len(rows))
+	}
+}
@@ -1,12 +1,22 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
-	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
 	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
 	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -21,32 +31,113 @@ type RegisterRequest struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Role is "player" or "coach" and defaults to "player"
+	Role string `json:"role"`
 }
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  models.User  `json:"user"`
+	TokenResponse
+	User models.User `json:"user"`
+}
+
+// TokenResponse represents a freshly issued access and refresh token pair
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// RefreshRequest represents the token refresh and logout request body
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// dummyPasswordHash is compared against when a login email is unknown, so that
+// the response time does not reveal whether an account exists
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("tennis-tracker-dummy-password"), bcrypt.DefaultCost)
+
+// Rate limits of the unauthenticated auth endpoints, each request to which
+// costs a bcrypt hash or an email. Every client IP and account email has its
+// own bucket per endpoint.
+var (
+	authIPLimit    = ratelimit.Limit{Burst: 20, Every: 6 * time.Second}
+	authEmailLimit = ratelimit.Limit{Burst: 10, Every: 30 * time.Second}
+)
+
+// loginLockout locks an account email out of logging in after repeated
+// failures, for a minute at first and up to an hour
+var loginLockout = ratelimit.LockoutPolicy{After: 5, Base: time.Minute, Max: time.Hour, Reset: 24 * time.Hour}
+
+// loginLockoutKey returns the rate limit key counting the failed logins of an email
+func loginLockoutKey(email string) string {
+	return "login:lockout:" + email
+}
+
+// rateLimitSweepInterval is how often SweepRateLimits cleans up
+const rateLimitSweepInterval = 5 * time.Minute
+
+// SweepRateLimits periodically deletes the rate limit buckets and failed
+// login counts that no longer limit anyone, until ctx is done
+func (h *Handler) SweepRateLimits(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.Limits.Sweep(ctx, loginLockout.Reset); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to sweep rate limits: %v", err)
+			}
+		}
+	}
 }
 
 // Register handles user registration
-func Register(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Validate input
-	if req.Email == "" || req.Password == "" {
-		RespondWithError(w, http.StatusBadRequest, "Email and password are required")
+	req.Email = models.NormalizeEmail(req.Email)
+	if req.Email == "" {
+		RespondWithError(w, r, apperr.Invalid("email", "Email is required"))
+		return
+	}
+	if req.Password == "" {
+		RespondWithError(w, r, apperr.Invalid("password", "Password is required"))
+		return
+	}
+	if err := models.ValidateEmail(req.Email); err != nil {
+		RespondWithError(w, r, apperr.Invalid("email", "Invalid email address"))
+		return
+	}
+	if err := models.ValidatePassword(req.Password); err != nil {
+		RespondWithError(w, r, apperr.Invalid("password", "Password must be 8-72 characters and contain at least one letter and one digit"))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := models.ValidateName(req.Name); err != nil {
+		RespondWithError(w, r, apperr.Invalid("name", "Name must be at most 255 characters without control characters"))
+		return
+	}
+	if req.Role == "" {
+		req.Role = models.RolePlayer
+	}
+	if !models.IsAssignableRole(req.Role) {
+		RespondWithError(w, r, apperr.Invalid("role", "Role must be player or coach"))
 		return
 	}
 
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		RespondWithError(w, r, apperr.Internal(err, "Failed to hash password"))
 		return
 	}
 
@@ -55,112 +146,295 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		Name:         req.Name,
 		Email:        req.Email,
 		PasswordHash: string(hashedPassword),
+		Role:         req.Role,
 	}
 
-	// TODO: Save user to database
-	// For now, we'll just respond with success
+	if err := h.Users.Create(r.Context(), &user); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			RespondWithError(w, r, apperr.Conflict("An account with that email already exists"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to create user")
+		return
+	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID)
+	// Generate access and refresh tokens
+	tokens, err := h.issueTokens(r.Context(), user.ID)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		RespondWithServiceError(w, r, err, "Failed to generate token")
 		return
 	}
 
-	// Return user and token
+	// Return user and tokens
 	RespondWithJSON(w, http.StatusCreated, AuthResponse{
-		Token: token,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          user,
 	})
 }
 
 // Login handles user login
-func Login(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Validate input
-	if req.Email == "" || req.Password == "" {
-		RespondWithError(w, http.StatusBadRequest, "Email and password are required")
+	if req.Email == "" {
+		RespondWithError(w, r, apperr.Invalid("email", "Email is required"))
+		return
+	}
+	if req.Password == "" {
+		RespondWithError(w, r, apperr.Invalid("password", "Password is required"))
 		return
 	}
 
-	// TODO: Get user from database
-	// For now, we'll just simulate a user
-	user := models.User{
-		ID:           1,
-		Name:         "Test User",
-		Email:        req.Email,
-		PasswordHash: "$2a$10$GckdTPS05LnUmMSxH4g4peVKqEoR8h1WUSLfFrS0WkpLcKTVQSfQq", // "password"
+	// Refuse emails locked out by failed logins before spending a bcrypt hash.
+	// Unknown emails are locked out too, so lockouts do not reveal accounts.
+	email := models.NormalizeEmail(req.Email)
+	lockoutKey := loginLockoutKey(email)
+	wait, err := h.Limits.LockedOut(r.Context(), lockoutKey)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to check login attempts")
+		return
+	}
+	if wait > 0 {
+		middleware.RespondRateLimited(w, r, wait, "Too many failed login attempts")
+		return
+	}
+
+	// Look up the user. Unknown emails are still checked against a dummy hash
+	// so that both failure paths take the same time and return the same response.
+	user, err := h.Users.GetByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		RespondWithServiceError(w, r, err, "Failed to look up user")
+		return
+	}
+
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
 	}
 
 	// Verify password
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
-		RespondWithError(w, http.StatusUnauthorized, "Invalid email or password")
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password)); err != nil || user == nil {
+		if _, err := h.Limits.RecordFailure(r.Context(), lockoutKey, loginLockout); err != nil {
+			RespondWithServiceError(w, r, err, "Failed to record login attempt")
+			return
+		}
+		RespondWithError(w, r, apperr.Unauthorized("Invalid email or password"))
+		return
+	}
+
+	if err := h.Limits.ClearFailures(r.Context(), lockoutKey); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to record login")
+		return
+	}
+
+	if err := h.Users.UpdateLastLogin(r.Context(), user); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to record login")
 		return
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID)
+	// Generate access and refresh tokens
+	tokens, err := h.issueTokens(r.Context(), user.ID)
 	if err != nil {
-		RespondWithError(w, http.StatusInternalServerError, "Failed to generate token")
+		RespondWithServiceError(w, r, err, "Failed to generate token")
 		return
 	}
 
-	// Return user and token
+	// Return user and tokens
 	RespondWithJSON(w, http.StatusOK, AuthResponse{
-		Token: token,
-		User:  user,
+		TokenResponse: *tokens,
+		User:          *user,
 	})
 }
 
+// RefreshToken exchanges a refresh token for a new access token and a rotated refresh token
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if req.RefreshToken == "" {
+		RespondWithError(w, r, apperr.Invalid("refresh_token", "Refresh token is required"))
+		return
+	}
+
+	refreshToken, userID, err := h.Tokens.RotateRefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenInvalid) || errors.Is(err, models.ErrRefreshTokenReused) {
+			RespondWithError(w, r, apperr.Unauthorized("Invalid refresh token"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to refresh token")
+		return
+	}
+
+	token, err := middleware.GenerateToken(h.JWTSecret, userID)
+	if err != nil {
+		RespondWithError(w, r, apperr.Internal(err, "Failed to generate token"))
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+	})
+}
+
+// Logout revokes the current access token and the family of the given refresh
+// token. Without a refresh token every refresh token of the user is revoked.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, err := middleware.GetClaims(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	// The body is optional
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	if req.RefreshToken != "" {
+		if _, err := h.Tokens.RevokeFamily(r.Context(), claims.UserID, req.RefreshToken); err != nil {
+			RespondWithServiceError(w, r, err, "Failed to log out")
+			return
+		}
+	} else if err := h.Tokens.RevokeAllForUser(r.Context(), claims.UserID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to log out")
+		return
+	}
+
+	if err := h.Tokens.RevokeAccessToken(r.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to log out")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Logged out successfully",
+	})
+}
+
+// issueTokens generates an access token and starts a new refresh token family for a user
+func (h *Handler) issueTokens(ctx context.Context, userID int) (*TokenResponse, error) {
+	token, err := middleware.GenerateToken(h.JWTSecret, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := h.Tokens.CreateRefreshToken(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(middleware.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
 // ForgotPassword handles password reset requests
-func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Validate email
+	req.Email = models.NormalizeEmail(req.Email)
 	if req.Email == "" {
-		RespondWithError(w, http.StatusBadRequest, "Email is required")
+		RespondWithError(w, r, apperr.Invalid("email", "Email is required"))
 		return
 	}
 
-	// TODO: Send password reset email
-	// For now, we'll just respond with success
+	// The response is the same whether or not the account exists, and the
+	// email is sent in the background so timing does not reveal it either
+	user, err := h.Users.GetByEmail(r.Context(), req.Email)
+	if err == nil {
+		go h.sendPasswordReset(user)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Failed to look up user for password reset: %v", err)
+	}
 
 	RespondWithJSON(w, http.StatusOK, SuccessResponse{
 		Message: "If an account with that email exists, a password reset link has been sent",
 	})
 }
 
+// sendPasswordReset issues a reset token for the user and emails them the link
+func (h *Handler) sendPasswordReset(user *models.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := h.Resets.Create(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return
+	}
+
+	link := strings.TrimRight(h.AppURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+
+	err = h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Tennis Tracker password",
+		Body: "Someone asked to reset the password for your Tennis Tracker account.\n\n" +
+			"Open this link within the next hour to choose a new password:\n\n" + link + "\n\n" +
+			"If it wasn't you, you can ignore this email.",
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email: %v", err)
+	}
+}
+
 // ResetPassword handles password reset
-func ResetPassword(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Token    string `json:"token"`
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
 		return
 	}
 
 	// Validate input
-	if req.Token == "" || req.Password == "" {
-		RespondWithError(w, http.StatusBadRequest, "Token and password are required")
+	if req.Token == "" {
+		RespondWithError(w, r, apperr.Invalid("token", "Token is required"))
+		return
+	}
+	if err := models.ValidatePassword(req.Password); err != nil {
+		RespondWithError(w, r, apperr.Invalid("password", "Password must be 8-72 characters and contain at least one letter and one digit"))
 		return
 	}
 
-	// TODO: Verify token and update password
-	// For now, we'll just respond with success
+	// Hash before consuming the token so a hashing failure doesn't burn it
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		RespondWithError(w, r, apperr.Internal(err, "Failed to hash password"))
+		return
+	}
+
+	// Consuming the token, setting the password and logging out every existing
+	// session happen in one transaction
+	if err := h.Resets.Reset(r.Context(), req.Token, string(hashedPassword)); err != nil {
+		if errors.Is(err, models.ErrResetTokenInvalid) {
+			RespondWithError(w, r, apperr.Invalid("token", "Invalid or expired reset token"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to reset password")
+		return
+	}
 
 	RespondWithJSON(w, http.StatusOK, SuccessResponse{
 		Message: "Password has been reset successfully",
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// InviteRequest represents the coach/player invitation request body
+type InviteRequest struct {
+	Email string `json:"email"`
+}
+
+// GetCoachedPlayers returns the players linked to the authenticated coach,
+// including invitations waiting for the coach to accept
+func (h *Handler) GetCoachedPlayers(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	links, err := h.Coaches.GetByCoachID(r.Context(), userID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve players")
+		return
+	}
+	links = withoutSentInvites(links, userID)
+
+	RespondWithJSON(w, http.StatusOK, links)
+}
+
+// GetCoaches returns the coaches linked to the authenticated user, including
+// invitations waiting for the user to accept
+func (h *Handler) GetCoaches(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	links, err := h.Coaches.GetByPlayerID(r.Context(), userID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve coaches")
+		return
+	}
+	links = withoutSentInvites(links, userID)
+
+	RespondWithJSON(w, http.StatusOK, links)
+}
+
+// withoutSentInvites drops the pending invitations sent by the user. Until the
+// other side accepts, the inviter is not told whether they have an account.
+func withoutSentInvites(links []models.CoachLink, userID int) []models.CoachLink {
+	visible := []models.CoachLink{}
+	for _, link := range links {
+		if link.Status == models.LinkPending && link.InvitedBy == userID {
+			continue
+		}
+		visible = append(visible, link)
+	}
+	return visible
+}
+
+// inviteSentMessage answers every invitation, so that inviting does not
+// reveal which emails have accounts
+const inviteSentMessage = "If an account with that email can be invited, an invitation has been sent"
+
+// InvitePlayer lets a coach invite a player by email. The player gets access
+// to nothing; the coach gets read access once the player accepts.
+func (h *Handler) InvitePlayer(w http.ResponseWriter, r *http.Request) {
+	coach, invitee, ok := h.readInvite(w, r)
+	if !ok {
+		return
+	}
+
+	if coach.Role != models.RoleCoach {
+		RespondWithError(w, r, apperr.Forbidden("Only coaches can invite players"))
+		return
+	}
+
+	if invitee != nil {
+		if !h.createInvite(w, r, coach, invitee, coach.ID, invitee.ID) {
+			return
+		}
+	}
+	RespondWithJSON(w, http.StatusAccepted, SuccessResponse{Message: inviteSentMessage})
+}
+
+// InviteCoach lets a player invite a coach by email, granting the coach read
+// access once they accept. Users who are not coaches cannot be invited.
+func (h *Handler) InviteCoach(w http.ResponseWriter, r *http.Request) {
+	player, invitee, ok := h.readInvite(w, r)
+	if !ok {
+		return
+	}
+
+	if invitee != nil && invitee.Role == models.RoleCoach {
+		if !h.createInvite(w, r, player, invitee, invitee.ID, player.ID) {
+			return
+		}
+	}
+	RespondWithJSON(w, http.StatusAccepted, SuccessResponse{Message: inviteSentMessage})
+}
+
+// AcceptCoachLink accepts an invitation sent to the authenticated user
+func (h *Handler) AcceptCoachLink(w http.ResponseWriter, r *http.Request) {
+	// The inviter cannot see their own pending invitation, so only the other side gets here
+	_, link, ok := h.involvedCoachLink(w, r)
+	if !ok {
+		return
+	}
+
+	if link.Status != models.LinkPending {
+		RespondWithError(w, r, apperr.Conflict("Invitation has already been accepted"))
+		return
+	}
+
+	if err := h.Coaches.Accept(r.Context(), link); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to accept invitation")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, link)
+}
+
+// DeleteCoachLink declines an invitation or ends a coaching relationship.
+// Either side may end one.
+func (h *Handler) DeleteCoachLink(w http.ResponseWriter, r *http.Request) {
+	_, link, ok := h.involvedCoachLink(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Coaches.Delete(r.Context(), link.ID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to remove coach link")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Coach link removed successfully",
+	})
+}
+
+// readInvite loads the authenticated user and the user invited by email, which
+// is nil if there is no account with that email. When ok is false an error
+// response has already been written.
+func (h *Handler) readInvite(w http.ResponseWriter, r *http.Request) (inviter, invitee *models.User, ok bool) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return nil, nil, false
+	}
+
+	var req InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return nil, nil, false
+	}
+	req.Email = models.NormalizeEmail(req.Email)
+	if req.Email == "" {
+		RespondWithError(w, r, apperr.Invalid("email", "Email is required"))
+		return nil, nil, false
+	}
+
+	inviter, err = h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, r, apperr.NotFound("User not found"))
+		return nil, nil, false
+	}
+
+	if req.Email == inviter.Email {
+		RespondWithError(w, r, apperr.Invalid("email", "You cannot invite yourself"))
+		return nil, nil, false
+	}
+
+	invitee, err = h.Users.GetByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		RespondWithServiceError(w, r, err, "Failed to retrieve user")
+		return nil, nil, false
+	}
+
+	return inviter, invitee, true
+}
+
+// createInvite stores a pending link and notifies the invitee. An existing
+// invitation or link is left as it is. When ok is false an error response
+// has already been written.
+func (h *Handler) createInvite(w http.ResponseWriter, r *http.Request, inviter, invitee *models.User, coachID, playerID int) (ok bool) {
+	_, err := h.Coaches.Invite(r.Context(), coachID, playerID, inviter.ID)
+	if errors.Is(err, models.ErrLinkExists) {
+		return true
+	}
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to create invitation")
+		return false
+	}
+
+	go h.sendCoachInvite(inviter, invitee)
+	return true
+}
+
+// sendCoachInvite emails the invitee about a new coaching invitation
+func (h *Handler) sendCoachInvite(inviter, invitee *models.User) {
+	name := inviter.Name
+	if name == "" {
+		name = inviter.Email
+	}
+
+	what := "coach you"
+	if inviter.Role != models.RoleCoach {
+		what = "be their coach"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := h.Mailer.Send(ctx, mailer.Message{
+		To:      invitee.Email,
+		Subject: name + " invited you on Tennis Tracker",
+		Body: name + " invited you to " + what + " on Tennis Tracker.\n\n" +
+			"Sign in to accept or decline the invitation:\n\n" + h.AppURL + "\n",
+	})
+	if err != nil {
+		log.Printf("Failed to send coach invitation email: %v", err)
+	}
+}
+
+// involvedCoachLink loads the coach link named by the id URL parameter and
+// checks that the authenticated user is its coach or player. Pending
+// invitations the user sent are hidden as in the lists. When ok is false an
+// error response has already been written.
+func (h *Handler) involvedCoachLink(w http.ResponseWriter, r *http.Request) (userID int, link *models.CoachLink, ok bool) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return 0, nil, false
+	}
+
+	id, err := URLParamInt(r, "id")
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid coach link ID"))
+		return 0, nil, false
+	}
+
+	link, err = h.Coaches.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Coach link not found", "Failed to retrieve coach link")
+		return 0, nil, false
+	}
+
+	if !link.Involves(userID) || (link.Status == models.LinkPending && link.InvitedBy == userID) {
+		RespondWithError(w, r, apperr.NotFound("Coach link not found"))
+		return 0, nil, false
+	}
+
+	return userID, link, true
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/notify"
+)
+
+// CommentRequest represents the create/update comment request body. ErrorID
+// and ParentID are only read when creating a comment.
+type CommentRequest struct {
+	Body     string `json:"body"`
+	ErrorID  *int   `json:"error_id,omitempty"`
+	ParentID *int   `json:"parent_id,omitempty"`
+}
+
+// validate normalizes the request and checks the body
+func (req *CommentRequest) validate() error {
+	req.Body = strings.TrimSpace(req.Body)
+
+	if req.Body == "" {
+		return apperr.Invalid("body", "Comment body is required")
+	}
+	if utf8.RuneCountInString(req.Body) > models.MaxCommentLength {
+		return apperr.Invalid("body", "Comment must be at most "+strconv.Itoa(models.MaxCommentLength)+" characters")
+	}
+	return nil
+}
+
+// GetComments returns the comments of a session the authenticated user may
+// view, oldest first. The error_id query parameter limits them to the comments
+// on one error entry. Replies carry the ID of their parent so clients can
+// build the threads.
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var errorID *int
+	if value := r.URL.Query().Get("error_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			RespondWithError(w, r, apperr.Invalid("error_id", "Invalid error ID"))
+			return
+		}
+		errorID = &id
+	}
+
+	comments, err := h.Comments.GetBySessionID(r.Context(), session.ID, errorID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve comments")
+		return
+	}
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, comments)
+}
+
+// CreateComment adds a comment to a session the authenticated user may view,
+// on the session itself, on one of its error entries or in reply to another
+// comment. Replies take the target of their parent.
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	comment := models.Comment{
+		SessionID: session.ID,
+		ErrorID:   req.ErrorID,
+		AuthorID:  userID,
+		Body:      req.Body,
+	}
+
+	var parent *models.Comment
+	if req.ParentID != nil {
+		parent, err = h.Comments.GetByID(r.Context(), *req.ParentID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			RespondWithServiceError(w, r, err, "Failed to retrieve parent comment")
+			return
+		}
+		if err != nil || parent.SessionID != session.ID {
+			RespondWithError(w, r, apperr.Invalid("parent_id", "Parent comment not found"))
+			return
+		}
+		comment.ParentID = &parent.ID
+		comment.ErrorID = parent.ErrorID
+	} else if req.ErrorID != nil {
+		entry, err := h.Errors.GetByID(r.Context(), *req.ErrorID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			RespondWithServiceError(w, r, err, "Failed to retrieve error")
+			return
+		}
+		if err != nil || entry.SessionID != session.ID {
+			RespondWithError(w, r, apperr.Invalid("error_id", "Error not found"))
+			return
+		}
+	}
+
+	if err := h.Comments.Create(r.Context(), &comment); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to create comment")
+		return
+	}
+
+	recipients := []int{session.UserID}
+	if parent != nil {
+		recipients = append(recipients, parent.AuthorID)
+	}
+	go h.notifyComment(session, &comment, recipients)
+
+	RespondWithJSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment edits a comment written by the authenticated user
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, _, comment, ok := h.sessionComment(w, r)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userID {
+		RespondWithError(w, r, apperr.Forbidden("Only the author can edit a comment"))
+		return
+	}
+
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	comment.Body = req.Body
+
+	if err := h.Comments.Update(r.Context(), comment); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to update comment")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment deletes a comment and its replies. Authors can delete their
+// own comments, and players any comment on their sessions.
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, session, comment, ok := h.sessionComment(w, r)
+	if !ok {
+		return
+	}
+
+	if comment.AuthorID != userID && session.UserID != userID {
+		RespondWithError(w, r, apperr.Forbidden("Only the author or the session owner can delete a comment"))
+		return
+	}
+
+	if err := h.Comments.Delete(r.Context(), comment.ID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to delete comment")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Comment deleted successfully",
+	})
+}
+
+// sessionComment loads the comment named by the commentID URL parameter and
+// checks that it belongs to the session in the id URL parameter, which the
+// authenticated user must be able to view. When ok is false an error response
+// has already been written.
+func (h *Handler) sessionComment(w http.ResponseWriter, r *http.Request) (userID int, session *models.Session, comment *models.Comment, ok bool) {
+	session, ok = h.viewableSession(w, r, "id")
+	if !ok {
+		return 0, nil, nil, false
+	}
+
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return 0, nil, nil, false
+	}
+
+	id, err := URLParamInt(r, "commentID")
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid comment ID"))
+		return 0, nil, nil, false
+	}
+
+	comment, err = h.Comments.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Comment not found", "Failed to retrieve comment")
+		return 0, nil, nil, false
+	}
+
+	if comment.SessionID != session.ID {
+		RespondWithError(w, r, apperr.NotFound("Comment not found"))
+		return 0, nil, nil, false
+	}
+
+	return userID, session, comment, true
+}
+
+// notifyComment passes a new comment to the notification hooks, once for each
+// recipient other than its author who can still view the session
+func (h *Handler) notifyComment(session *models.Session, comment *models.Comment, recipients []int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	actor, err := h.Users.GetByID(ctx, comment.AuthorID)
+	if err != nil {
+		log.Printf("Failed to load comment author: %v", err)
+		return
+	}
+
+	seen := map[int]bool{comment.AuthorID: true}
+	for _, id := range recipients {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		allowed, err := h.Coaches.CanView(ctx, id, session.UserID)
+		if err != nil || !allowed {
+			continue
+		}
+
+		recipient, err := h.Users.GetByID(ctx, id)
+		if err != nil {
+			log.Printf("Failed to load comment notification recipient: %v", err)
+			continue
+		}
+
+		err = h.Notifier.Notify(ctx, notify.Event{
+			Type:      notify.EventCommentCreated,
+			Recipient: recipient,
+			Actor:     actor,
+			Session:   session,
+			Comment:   comment,
+		})
+		if err != nil {
+			log.Printf("Failed to send comment notification: %v", err)
+		}
+	}
+}
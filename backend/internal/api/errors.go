@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// ErrorRequest represents the log/update error request body
+type ErrorRequest struct {
+	Count      int        `json:"count"`
+	Stroke     string     `json:"stroke,omitempty"`
+	Side       string     `json:"side,omitempty"`
+	Outcome    string     `json:"outcome,omitempty"`
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
+}
+
+// validate checks the request fields
+func (req *ErrorRequest) validate() error {
+	if req.Count < 1 {
+		return apperr.Invalid("count", "Count must be at least 1")
+	}
+	return nil
+}
+
+// GetErrors returns all error entries of a session the authenticated user may view
+func (h *Handler) GetErrors(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "sessionID")
+	if !ok {
+		return
+	}
+
+	entries, err := h.Errors.GetBySessionID(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve errors")
+		return
+	}
+	if entries == nil {
+		entries = []models.ErrorEntry{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, entries)
+}
+
+// LogError adds an error entry to a session owned by the authenticated user
+func (h *Handler) LogError(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "sessionID")
+	if !ok {
+		return
+	}
+
+	var req ErrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	entry := models.ErrorEntry{
+		SessionID: session.ID,
+		Count:     req.Count,
+		Stroke:    req.Stroke,
+		Side:      req.Side,
+		Outcome:   req.Outcome,
+	}
+	if req.RecordedAt != nil {
+		entry.RecordedAt = *req.RecordedAt
+	}
+	if err := entry.ValidateCategories(); err != nil {
+		RespondWithError(w, r, err.Invalid())
+		return
+	}
+
+	if err := h.Errors.Create(r.Context(), &entry); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to log error")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, entry)
+}
+
+// UpdateError updates an error entry of a session owned by the authenticated user
+func (h *Handler) UpdateError(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.ownedErrorEntry(w, r)
+	if !ok {
+		return
+	}
+
+	var req ErrorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	entry.Count = req.Count
+	entry.Stroke = req.Stroke
+	entry.Side = req.Side
+	entry.Outcome = req.Outcome
+	if req.RecordedAt != nil {
+		entry.RecordedAt = *req.RecordedAt
+	}
+	if err := entry.ValidateCategories(); err != nil {
+		RespondWithError(w, r, err.Invalid())
+		return
+	}
+
+	if err := h.Errors.Update(r.Context(), entry); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to update error")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, entry)
+}
+
+// DeleteError deletes an error entry of a session owned by the authenticated user
+func (h *Handler) DeleteError(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.ownedErrorEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Errors.Delete(r.Context(), entry.ID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to delete error")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Error deleted successfully",
+	})
+}
+
+// ownedErrorEntry loads the error entry named by the id URL parameter and
+// checks that it belongs to the session in the sessionID URL parameter, which
+// in turn must belong to the authenticated user. When ok is false an error
+// response has already been written.
+func (h *Handler) ownedErrorEntry(w http.ResponseWriter, r *http.Request) (entry *models.ErrorEntry, ok bool) {
+	session, ok := h.ownedSession(w, r, "sessionID")
+	if !ok {
+		return nil, false
+	}
+
+	id, err := URLParamInt(r, "id")
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid error ID"))
+		return nil, false
+	}
+
+	entry, err = h.Errors.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Error not found", "Failed to retrieve error")
+		return nil, false
+	}
+
+	if entry.SessionID != session.ID {
+		RespondWithError(w, r, apperr.NotFound("Error not found"))
+		return nil, false
+	}
+
+	return entry, true
+}
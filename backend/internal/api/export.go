@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Export formats
+const (
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportNDJSON = "ndjson"
+)
+
+// exportCSVHeader names the CSV columns: the session fields followed by those
+// of one error entry
+var exportCSVHeader = []string{
+	"session_id", "session_client_id", "session_name", "opponent_id", "opponent_name", "session_date",
+	"session_created_at", "session_updated_at", "session_error_count",
+	"error_id", "error_client_id", "count", "stroke", "side", "outcome", "recorded_at",
+	"error_created_at", "error_updated_at",
+}
+
+// sessionExporter writes exported sessions in one format
+type sessionExporter interface {
+	begin() error
+	write(session *models.SessionWithErrors) error
+	end() error
+}
+
+// ExportData streams every session of the authenticated user, or of a player
+// they coach given by player_id, with its error entries. The format query
+// parameter picks csv, json (the default) or ndjson. CSV has one row per
+// error entry, and one with empty error columns for a session without any.
+func (h *Handler) ExportData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.viewedPlayer(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportJSON
+	}
+
+	var (
+		exporter    sessionExporter
+		contentType string
+	)
+	switch format {
+	case ExportCSV:
+		exporter = &csvExporter{w: csv.NewWriter(w)}
+		contentType = "text/csv; charset=utf-8"
+	case ExportJSON:
+		exporter = &jsonExporter{w: w, enc: json.NewEncoder(w)}
+		contentType = "application/json"
+	case ExportNDJSON:
+		exporter = &jsonExporter{w: w, enc: json.NewEncoder(w), lines: true}
+		contentType = "application/x-ndjson"
+	default:
+		RespondWithError(w, r, apperr.Invalid("format", "Format must be csv, json or ndjson"))
+		return
+	}
+
+	// Large exports take longer than the server's write timeout allows
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the write deadline for an export: %v", err)
+	}
+
+	filename := "tennis-tracker-" + time.Now().Format("2006-01-02") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so failures from here on can only cut the export short
+	if err := exporter.begin(); err != nil {
+		return
+	}
+	err := h.Sessions.Export(r.Context(), userID, exporter.write)
+	if err != nil {
+		log.Printf("Export failed for user %d: %v", userID, err)
+		return
+	}
+	if err := exporter.end(); err != nil {
+		log.Printf("Export failed for user %d: %v", userID, err)
+	}
+}
+
+// csvExporter writes one row per error entry
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write(exportCSVHeader)
+}
+
+func (e *csvExporter) write(session *models.SessionWithErrors) error {
+	sessionFields := []string{
+		strconv.Itoa(session.ID),
+		optionalString(session.ClientID),
+		csvText(session.Name),
+		optionalInt(session.OpponentID),
+		csvText(session.OpponentName),
+		session.SessionDate.Format(time.RFC3339),
+		session.CreatedAt.Format(time.RFC3339),
+		session.UpdatedAt.Format(time.RFC3339),
+		strconv.Itoa(session.ErrorCount),
+	}
+
+	if len(session.Errors) == 0 {
+		row := append(sessionFields, make([]string, len(exportCSVHeader)-len(sessionFields))...)
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range session.Errors {
+		row := append(sessionFields[:len(sessionFields):len(sessionFields)],
+			strconv.Itoa(entry.ID),
+			optionalString(entry.ClientID),
+			strconv.Itoa(entry.Count),
+			entry.Stroke,
+			entry.Side,
+			entry.Outcome,
+			entry.RecordedAt.Format(time.RFC3339),
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.UpdatedAt.Format(time.RFC3339),
+		)
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	// Hand each session to the client as it is read
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExporter writes sessions as a JSON array, or as newline-delimited JSON
+// with one session per line
+type jsonExporter struct {
+	w     http.ResponseWriter
+	enc   *json.Encoder
+	lines bool
+	count int
+}
+
+func (e *jsonExporter) begin() error {
+	if e.lines {
+		return nil
+	}
+	_, err := e.w.Write([]byte("["))
+	return err
+}
+
+func (e *jsonExporter) write(session *models.SessionWithErrors) error {
+	if !e.lines && e.count > 0 {
+		if _, err := e.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	// Encode ends every value with a newline
+	return e.enc.Encode(session)
+}
+
+func (e *jsonExporter) end() error {
+	if e.lines {
+		return nil
+	}
+	_, err := e.w.Write([]byte("]\n"))
+	return err
+}
+
+// csvText formats free text for CSV. Spreadsheets run cells starting with
+// =, +, - or @ as formulas, and some also those starting with a tab or
+// carriage return, so such cells are prefixed with a quote to keep them text.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// optionalString formats a nullable string column for CSV
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// optionalInt formats a nullable integer column for CSV
+func optionalInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/memory"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/notify"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// Handler holds the services used by the API handlers
+type Handler struct {
+	Users     models.UserStore
+	Sessions  models.SessionStore
+	Errors    models.ErrorStore
+	Shares    models.ShareStore
+	Opponents models.OpponentStore
+	Tokens    models.TokenStore
+	Coaches   models.CoachStore
+	// Limits throttles the auth endpoints and counts failed logins
+	Limits ratelimit.Store
+	// The services below need Postgres and are nil when InMemory is set
+	Points   *models.PointService
+	Sync     *models.SyncService
+	Changes  *models.ChangeService
+	Comments *models.CommentService
+	Resets   *models.PasswordResetService
+	// InMemory is set when the stores keep their data in memory; the
+	// endpoints that need the Postgres-only services then answer 501
+	InMemory bool
+	Mailer   mailer.Mailer
+	// Notifier receives events such as new comments; add hooks with notify.Hooks
+	Notifier notify.Notifier
+	// JWTSecret signs and verifies access tokens
+	JWTSecret []byte
+	// AppURL is the base URL of the frontend, used in links sent by email
+	AppURL string
+}
+
+// NewHandler creates a new Handler backed by the given database and mailer
+func NewHandler(cfg *config.Config, db *database.DB, mail mailer.Mailer) *Handler {
+	return &Handler{
+		Users:     &models.UserService{DB: db},
+		Sessions:  &models.SessionService{DB: db},
+		Errors:    &models.ErrorService{DB: db},
+		Shares:    &models.ShareService{DB: db},
+		Opponents: &models.OpponentService{DB: db},
+		Tokens:    &models.TokenService{DB: db},
+		Coaches:   &models.CoachService{DB: db},
+		Limits:    &models.RateLimitService{DB: db},
+		Points:    &models.PointService{DB: db},
+		Sync:      &models.SyncService{DB: db},
+		Changes:   &models.ChangeService{DB: db},
+		Comments:  &models.CommentService{DB: db},
+		Resets:    &models.PasswordResetService{DB: db},
+		Mailer:    mail,
+		Notifier:  notify.Hooks{&notify.MailNotifier{Mailer: mail, AppURL: cfg.AppURL}},
+		JWTSecret: []byte(cfg.JWTSecret),
+		AppURL:    cfg.AppURL,
+	}
+}
+
+// NewMemoryHandler creates a Handler backed by an in-memory store, for demos,
+// frontend development and tests. Points, comments, sync and password resets
+// are not available.
+func NewMemoryHandler(cfg *config.Config, store *memory.Store, mail mailer.Mailer) *Handler {
+	return &Handler{
+		Users:     store.Users(),
+		Sessions:  store.Sessions(),
+		Errors:    store.Errors(),
+		Shares:    store.Shares(),
+		Opponents: store.Opponents(),
+		Tokens:    store.Tokens(),
+		Coaches:   store.Coaches(),
+		Limits:    store.RateLimits(),
+		InMemory:  true,
+		Mailer:    mail,
+		Notifier:  notify.Hooks{&notify.MailNotifier{Mailer: mail, AppURL: cfg.AppURL}},
+		JWTSecret: []byte(cfg.JWTSecret),
+		AppURL:    cfg.AppURL,
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/importer"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// MaxImportSize is the largest CSV file accepted by the import endpoint, in bytes
+const MaxImportSize = 10 << 20
+
+// ImportResponse summarizes an import, or what it would create on a dry run
+type ImportResponse struct {
+	DryRun       bool                       `json:"dry_run"`
+	SessionCount int                        `json:"session_count"`
+	EntryCount   int                        `json:"entry_count"`
+	ErrorCount   int                        `json:"error_count"` // Sum of the entry counts
+	Sessions     []models.SessionWithErrors `json:"sessions"`
+}
+
+// ImportSessions creates sessions and error entries for the authenticated
+// user from a CSV request body. Query parameters named after the importer
+// fields (date, session, opponent, errors, stroke, side, outcome) map them to
+// column headers, and date_format sets the Go layout of the dates. Nothing is
+// stored unless every row is valid, and nothing at all with dry_run=true,
+// which returns the sessions that would be created. Invalid rows are listed
+// in the lines member of the problem details.
+func (h *Handler) ImportSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	query := r.URL.Query()
+
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			RespondWithError(w, r, apperr.Invalid("dry_run", "dry_run must be true or false"))
+			return
+		}
+	}
+
+	mapping := importer.DefaultMapping()
+	for _, field := range importer.Fields {
+		if column := query.Get(field); column != "" {
+			if err := mapping.Set(field, column); err != nil {
+				RespondWithError(w, r, apperr.Invalid(field, err.Error()))
+				return
+			}
+		}
+	}
+	if format := query.Get("date_format"); format != "" {
+		mapping.DateFormat = format
+	}
+
+	// Large uploads take longer than the server's read timeout allows; their
+	// size is capped by MaxImportSize instead
+	if err := http.NewResponseController(w).SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear the read deadline for an import: %v", err)
+	}
+
+	sessions, lineErrors, err := importer.Read(http.MaxBytesReader(w, r.Body, MaxImportSize), mapping)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			RespondWithError(w, r, apperr.New(apperr.CodeTooLarge, "File is too large"))
+			return
+		}
+		RespondWithError(w, r, apperr.BadRequest("Invalid CSV file: "+err.Error()))
+		return
+	}
+	if len(lineErrors) > 0 {
+		RespondWithError(w, r, &apperr.Error{
+			Code:       apperr.CodeValidationFailed,
+			Message:    "Some rows are invalid; nothing was imported",
+			Extensions: map[string]interface{}{"lines": lineErrors},
+		})
+		return
+	}
+	if sessions == nil {
+		sessions = []models.SessionWithErrors{}
+	}
+
+	status := http.StatusOK
+	if !dryRun {
+		if err := h.Sessions.Import(r.Context(), userID, sessions); err != nil {
+			RespondWithServiceError(w, r, err, "Failed to import sessions")
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	entries, errorCount := importer.Totals(sessions)
+	RespondWithJSON(w, status, ImportResponse{
+		DryRun:       dryRun,
+		SessionCount: len(sessions),
+		EntryCount:   entries,
+		ErrorCount:   errorCount,
+		Sessions:     sessions,
+	})
+}
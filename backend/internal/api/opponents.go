@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/stats"
+)
+
+// OpponentRequest represents the rename opponent request body
+type OpponentRequest struct {
+	Name string `json:"name"`
+}
+
+// MergeOpponentsRequest represents the merge opponents request body
+type MergeOpponentsRequest struct {
+	SourceIDs []int `json:"source_ids"`
+}
+
+// OpponentSummary represents the head-to-head breakdown against an opponent
+type OpponentSummary struct {
+	Opponent models.Opponent    `json:"opponent"`
+	Summary  stats.Summary      `json:"summary"`
+	Trend    []stats.TrendPoint `json:"trend"`
+}
+
+// GetOpponents returns all opponents of the authenticated user
+func (h *Handler) GetOpponents(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	opponents, err := h.Opponents.GetByUserID(r.Context(), userID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve opponents")
+		return
+	}
+	if opponents == nil {
+		opponents = []models.Opponent{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, opponents)
+}
+
+// GetOpponentSummary returns session count, error averages and trend against an opponent
+func (h *Handler) GetOpponentSummary(w http.ResponseWriter, r *http.Request) {
+	opponent, ok := h.ownedOpponent(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := h.parseSessionFilter(r)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+	filter.Opponent = ""
+	filter.OpponentID = opponent.ID
+
+	totals, err := h.Sessions.GetTotalsByUserID(r.Context(), opponent.UserID, filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve statistics")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, OpponentSummary{
+		Opponent: *opponent,
+		Summary:  stats.Summarize(totals),
+		Trend:    stats.Trend(totals),
+	})
+}
+
+// UpdateOpponent renames an opponent of the authenticated user
+func (h *Handler) UpdateOpponent(w http.ResponseWriter, r *http.Request) {
+	opponent, ok := h.ownedOpponent(w, r)
+	if !ok {
+		return
+	}
+
+	var req OpponentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if models.NormalizeOpponentName(req.Name) == "" {
+		RespondWithError(w, r, apperr.Invalid("name", "Opponent name is required"))
+		return
+	}
+	if err := models.ValidateName(req.Name); err != nil {
+		RespondWithError(w, r, apperr.Invalid("name", "Opponent name must be at most 255 characters without control characters"))
+		return
+	}
+
+	if err := h.Opponents.Rename(r.Context(), opponent, req.Name); err != nil {
+		if errors.Is(err, models.ErrOpponentExists) {
+			RespondWithError(w, r, apperr.Conflict("Another opponent already has that name; merge them instead"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to update opponent")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, opponent)
+}
+
+// MergeOpponents moves the sessions of other opponents of the authenticated
+// user into this one and deletes the others
+func (h *Handler) MergeOpponents(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.ownedOpponent(w, r)
+	if !ok {
+		return
+	}
+
+	var req MergeOpponentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if len(req.SourceIDs) == 0 {
+		RespondWithError(w, r, apperr.Invalid("source_ids", "At least one source opponent is required"))
+		return
+	}
+
+	for _, id := range req.SourceIDs {
+		source, err := h.Opponents.GetByID(r.Context(), id)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			RespondWithServiceError(w, r, err, "Failed to retrieve opponent")
+			return
+		}
+		if err != nil || source.UserID != target.UserID {
+			RespondWithError(w, r, apperr.Invalid("source_ids", "Source opponent not found"))
+			return
+		}
+	}
+
+	if err := h.Opponents.Merge(r.Context(), target, req.SourceIDs); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to merge opponents")
+		return
+	}
+
+	merged, err := h.Opponents.GetByID(r.Context(), target.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve opponent")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, merged)
+}
+
+// ownedOpponent loads the opponent named by the id URL parameter and checks
+// that it belongs to the authenticated user. When ok is false an error
+// response has already been written.
+func (h *Handler) ownedOpponent(w http.ResponseWriter, r *http.Request) (opponent *models.Opponent, ok bool) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return nil, false
+	}
+
+	id, err := URLParamInt(r, "id")
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid opponent ID"))
+		return nil, false
+	}
+
+	opponent, err = h.Opponents.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Opponent not found", "Failed to retrieve opponent")
+		return nil, false
+	}
+
+	if opponent.UserID != userID {
+		RespondWithError(w, r, apperr.NotFound("Opponent not found"))
+		return nil, false
+	}
+
+	return opponent, true
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/scoring"
+)
+
+// PointRequest represents the log point request body
+type PointRequest struct {
+	Winner string         `json:"winner"`
+	Ending scoring.Ending `json:"ending"`
+}
+
+// MatchResponse represents the points of a session together with the derived score
+type MatchResponse struct {
+	Format scoring.Format `json:"format"`
+	Points []models.Point `json:"points"`
+	Score  scoring.Score  `json:"score"`
+}
+
+// PointResponse represents a newly logged point and the score after it
+type PointResponse struct {
+	Point models.Point  `json:"point"`
+	Score scoring.Score `json:"score"`
+}
+
+// GetPoints returns the points of a session the authenticated user may view and the derived score
+func (h *Handler) GetPoints(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	format, points, match, ok := h.replayMatch(w, r, session.ID)
+	if !ok {
+		return
+	}
+	if points == nil {
+		points = []models.Point{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, MatchResponse{
+		Format: format,
+		Points: points,
+		Score:  match.Score(),
+	})
+}
+
+// LogPoint appends a point to a session owned by the authenticated user
+func (h *Handler) LogPoint(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req PointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	winner, err := scoring.ParseCompetitor(req.Winner)
+	if err != nil {
+		RespondWithError(w, r, apperr.Invalid("winner", "Winner must be 'player' or 'opponent'"))
+		return
+	}
+
+	_, points, match, ok := h.replayMatch(w, r, session.ID)
+	if !ok {
+		return
+	}
+
+	if err := match.Play(winner, req.Ending); err != nil {
+		if errors.Is(err, scoring.ErrMatchOver) {
+			RespondWithError(w, r, apperr.Conflict("The match is already over"))
+			return
+		}
+		RespondWithError(w, r, apperr.Invalid("ending", "Invalid point: "+err.Error()))
+		return
+	}
+
+	point := models.Point{
+		SessionID: session.ID,
+		Seq:       len(points) + 1,
+		Winner:    winner,
+		Ending:    req.Ending,
+	}
+
+	if err := h.Points.Create(r.Context(), &point); err != nil {
+		if errors.Is(err, models.ErrPointConflict) {
+			RespondWithError(w, r, apperr.Conflict("Another point was logged at the same time, please retry"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to log point")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, PointResponse{
+		Point: point,
+		Score: match.Score(),
+	})
+}
+
+// UndoPoint removes the most recent point of a session owned by the authenticated user
+func (h *Handler) UndoPoint(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := h.Points.DeleteLast(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to undo point")
+		return
+	}
+	if !found {
+		RespondWithError(w, r, apperr.NotFound("No points have been logged"))
+		return
+	}
+
+	h.GetPoints(w, r)
+}
+
+// GetMatchFormat returns the match format of a session the authenticated user may view
+func (h *Handler) GetMatchFormat(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	format, err := h.Points.GetFormat(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve match format")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, format)
+}
+
+// UpdateMatchFormat sets the match format of a session owned by the authenticated user.
+// The points already logged must still form a valid match under the new format.
+func (h *Handler) UpdateMatchFormat(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var format scoring.Format
+	if err := json.NewDecoder(r.Body).Decode(&format); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := format.Validate(); err != nil {
+		RespondWithError(w, r, apperr.Invalid("format", "Invalid match format: "+err.Error()))
+		return
+	}
+
+	points, err := h.Points.GetBySessionID(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve points")
+		return
+	}
+	if _, err := scoring.Replay(format, models.ScoringPoints(points)); err != nil {
+		RespondWithError(w, r, apperr.Conflict("The logged points do not fit this format: "+err.Error()))
+		return
+	}
+
+	if err := h.Points.SetFormat(r.Context(), session.ID, format); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to update match format")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, format)
+}
+
+// replayMatch loads the format and points of a session and replays them
+// through the scoring engine. When ok is false an error response has already
+// been written.
+func (h *Handler) replayMatch(w http.ResponseWriter, r *http.Request, sessionID int) (format scoring.Format, points []models.Point, match *scoring.Match, ok bool) {
+	format, err := h.Points.GetFormat(r.Context(), sessionID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve match format")
+		return format, nil, nil, false
+	}
+
+	points, err = h.Points.GetBySessionID(r.Context(), sessionID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve points")
+		return format, nil, nil, false
+	}
+
+	match, err = scoring.Replay(format, models.ScoringPoints(points))
+	if err != nil {
+		RespondWithError(w, r, apperr.Internal(err, "Stored points do not form a valid match"))
+		return format, nil, nil, false
+	}
+
+	return format, points, match, true
+}
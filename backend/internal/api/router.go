@@ -2,86 +2,174 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
 	customMiddleware "github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
 )
 
-// NewRouter sets up and returns the router for the API
-func NewRouter(db *database.DB) http.Handler {
+// NewRouter sets up and returns the router for the API served by h
+func NewRouter(cfg *config.Config, h *Handler) http.Handler {
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(customMiddleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 	
 	// Custom middleware
 	r.Use(customMiddleware.JSONContentType)
 
-	// CORS configuration
+	// CORS configuration. Only the configured origins are trusted, as
+	// credentials are allowed.
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedOrigins:   cfg.AllowedOrigins(),
+		AllowedMethods:   cfg.CORS.AllowedMethods,
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
+	// Unknown routes get problem details like every other error
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		RespondWithError(w, r, apperr.NotFound("Not found"))
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		RespondWithError(w, r, apperr.New(apperr.CodeMethodNotAllowed, "Method not allowed"))
+	})
+
 	// Public routes
 	r.Group(func(r chi.Router) {
 		r.Get("/health", HealthCheck)
 		
 		// Auth endpoints
-		r.Post("/api/register", Register)
-		r.Post("/api/login", Login)
-		r.Post("/api/forgot-password", ForgotPassword)
-		r.Post("/api/reset-password", ResetPassword)
+		r.With(h.rateLimit("register")).Post("/api/register", h.Register)
+		r.With(h.rateLimit("login")).Post("/api/login", h.Login)
+		r.Post("/api/token/refresh", h.RefreshToken)
+		r.With(h.requireDatabase, h.rateLimit("forgot-password")).Post("/api/forgot-password", h.ForgotPassword)
+		r.With(h.requireDatabase).Post("/api/reset-password", h.ResetPassword)
 		
 		// Shared data endpoint (public)
-		r.Get("/api/shared/{token}", GetSharedSession)
+		r.Get("/api/shared/{token}", h.GetSharedSession)
 	})
 
 	// Protected routes
 	r.Group(func(r chi.Router) {
 		// Use authentication middleware
-		r.Use(customMiddleware.Authenticate)
+		r.Use(customMiddleware.Authenticate(h.JWTSecret, h.Tokens))
+		
+		// Auth endpoints
+		r.Post("/api/logout", h.Logout)
 		
 		// User endpoints
-		r.Get("/api/user", GetUser)
-		r.Put("/api/user", UpdateUser)
+		r.Get("/api/user", h.GetUser)
+		r.Put("/api/user", h.UpdateUser)
+		
+		// Admin endpoints
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(h.requireAdmin)
+			r.Put("/users/{id}/role", h.UpdateUserRole)
+		})
+		
+		// Statistics endpoints
+		r.Get("/api/stats", h.GetStats)
+		r.Get("/api/stats/trend", h.GetStatsTrend)
+		
+		// Export and import endpoints
+		r.Get("/api/export", h.ExportData)
+		r.Post("/api/import", h.ImportSessions)
+		
+		// Offline sync endpoints
+		r.With(h.requireDatabase).Post("/api/sync", h.SyncChanges)
+		r.With(h.requireDatabase).Get("/api/changes", h.GetChanges)
+		
+		// Coaching endpoints
+		r.Route("/api/coaching", func(r chi.Router) {
+			r.Get("/players", h.GetCoachedPlayers)
+			r.Post("/players", h.InvitePlayer)
+			r.Get("/coaches", h.GetCoaches)
+			r.Post("/coaches", h.InviteCoach)
+			r.Post("/{id}/accept", h.AcceptCoachLink)
+			r.Delete("/{id}", h.DeleteCoachLink)
+		})
+		
+		// Opponent endpoints
+		r.Route("/api/opponents", func(r chi.Router) {
+			r.Get("/", h.GetOpponents)
+			r.Put("/{id}", h.UpdateOpponent)
+			r.Get("/{id}/summary", h.GetOpponentSummary)
+			r.Post("/{id}/merge", h.MergeOpponents)
+		})
 		
 		// Session endpoints
 		r.Route("/api/sessions", func(r chi.Router) {
-			r.Get("/", GetSessions)
-			r.Post("/", CreateSession)
-			r.Get("/{id}", GetSession)
-			r.Put("/{id}", UpdateSession)
-			r.Delete("/{id}", DeleteSession)
+			r.Get("/", h.GetSessions)
+			r.Post("/", h.CreateSession)
+			r.Get("/{id}", h.GetSession)
+			r.Put("/{id}", h.UpdateSession)
+			r.Delete("/{id}", h.DeleteSession)
 			
 			// Error tracking endpoints
 			r.Route("/{sessionID}/errors", func(r chi.Router) {
-				r.Get("/", GetErrors)
-				r.Post("/", LogError)
-				r.Put("/{id}", UpdateError)
-				r.Delete("/{id}", DeleteError)
+				r.Get("/", h.GetErrors)
+				r.Post("/", h.LogError)
+				r.Put("/{id}", h.UpdateError)
+				r.Delete("/{id}", h.DeleteError)
+			})
+			
+			// Point-by-point match endpoints
+			r.Route("/{id}/points", func(r chi.Router) {
+				r.Use(h.requireDatabase)
+				r.Get("/", h.GetPoints)
+				r.Post("/", h.LogPoint)
+				r.Delete("/last", h.UndoPoint)
+				r.Get("/format", h.GetMatchFormat)
+				r.Put("/format", h.UpdateMatchFormat)
+			})
+			
+			// Comment endpoints
+			r.Route("/{id}/comments", func(r chi.Router) {
+				r.Use(h.requireDatabase)
+				r.Get("/", h.GetComments)
+				r.Post("/", h.CreateComment)
+				r.Put("/{commentID}", h.UpdateComment)
+				r.Delete("/{commentID}", h.DeleteComment)
 			})
 			
 			// Sharing endpoints
-			r.Post("/{id}/share", ShareSession)
-			r.Delete("/{id}/share", RemoveShare)
+			r.Get("/{id}/share", h.GetShares)
+			r.Post("/{id}/share", h.ShareSession)
+			r.Delete("/{id}/share", h.RemoveShare)
 		})
 	})
 
 	return r
 }
 
+// requireDatabase answers 501 for endpoints whose services need Postgres when
+// the handler runs on the in-memory stores
+func (h *Handler) requireDatabase(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.InMemory {
+			RespondWithError(w, r, apperr.New(apperr.CodeNotImplemented, "Not available without a database"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit throttles an auth endpoint per client IP and account email
+func (h *Handler) rateLimit(scope string) func(http.Handler) http.Handler {
+	return customMiddleware.RateLimit(h.Limits, scope, authIPLimit, authEmailLimit)
+}
+
 // HealthCheck handles the health check endpoint
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
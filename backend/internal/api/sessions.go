@@ -0,0 +1,374 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// SessionRequest represents the create/update session request body
+type SessionRequest struct {
+	Name         string    `json:"name"`
+	OpponentID   *int      `json:"opponent_id,omitempty"` // Takes precedence over OpponentName
+	OpponentName string    `json:"opponent_name"`
+	SessionDate  time.Time `json:"session_date"`
+}
+
+// validate normalizes the request and checks the required fields
+func (req *SessionRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.OpponentName = strings.TrimSpace(req.OpponentName)
+
+	if req.Name == "" {
+		return apperr.Invalid("name", "Session name is required")
+	}
+	if err := models.ValidateName(req.Name); err != nil {
+		return apperr.Invalid("name", "Session name must be at most 255 characters without control characters")
+	}
+	if err := models.ValidateName(req.OpponentName); err != nil {
+		return apperr.Invalid("opponent_name", "Opponent name must be at most 255 characters without control characters")
+	}
+	if req.SessionDate.IsZero() {
+		return apperr.Invalid("session_date", "Session date is required")
+	}
+	return nil
+}
+
+// GetSessions returns a page of the sessions of the authenticated user, or of
+// a player they coach given by player_id. Besides the filters read by
+// parseSessionFilter it takes sort (date, errors or name), order (asc or
+// desc), limit and cursor. The Link header points to the first page and, if
+// there is one, the next.
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.viewedPlayer(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := h.parseSessionListOptions(r)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	sessions, next, err := h.Sessions.List(r.Context(), userID, opts)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve sessions")
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+
+	links := []string{pageLink(r, "", "first")}
+	if next != nil {
+		links = append(links, pageLink(r, next.Encode(), "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+// parseSessionListOptions reads the filter, sort, order, limit and cursor
+// query parameters of the session list
+func (h *Handler) parseSessionListOptions(r *http.Request) (models.SessionListOptions, error) {
+	var opts models.SessionListOptions
+	q := r.URL.Query()
+
+	filter, err := h.parseSessionFilter(r)
+	if err != nil {
+		return opts, err
+	}
+	opts.Filter = filter
+
+	opts.Sort = q.Get("sort")
+	switch opts.Sort {
+	case "":
+		opts.Sort = models.SessionSortDate
+	case models.SessionSortDate, models.SessionSortErrors, models.SessionSortName:
+	default:
+		return opts, apperr.Invalid("sort", "Sort must be date, errors or name")
+	}
+
+	// Newest and most errors first, names alphabetically
+	opts.Desc = opts.Sort != models.SessionSortName
+	switch q.Get("order") {
+	case "":
+	case "asc":
+		opts.Desc = false
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, apperr.Invalid("order", "Order must be asc or desc")
+	}
+
+	opts.Limit = models.DefaultSessionPageSize
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > models.MaxSessionPageSize {
+			return opts, apperr.Invalid("limit", fmt.Sprintf("Limit must be between 1 and %d", models.MaxSessionPageSize))
+		}
+		opts.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := models.ParseSessionCursor(v)
+		if err != nil || cursor.Sort != opts.Sort || cursor.Desc != opts.Desc {
+			return opts, apperr.Invalid("cursor", "Invalid cursor")
+		}
+		opts.After = cursor
+	}
+
+	return opts, nil
+}
+
+// pageLink formats a Link header entry for the current request URL with the
+// cursor replaced, or removed if empty
+func pageLink(r *http.Request, cursor, rel string) string {
+	u := *r.URL
+	q := u.Query()
+	if cursor == "" {
+		q.Del("cursor")
+	} else {
+		q.Set("cursor", cursor)
+	}
+	u.RawQuery = q.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
+
+// CreateSession creates a new session for the authenticated user
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req SessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	session := models.Session{
+		UserID:       userID,
+		Name:         req.Name,
+		OpponentName: req.OpponentName,
+		SessionDate:  req.SessionDate,
+	}
+
+	if !h.resolveOpponent(w, r, &session, req.OpponentID) {
+		return
+	}
+
+	if err := h.Sessions.Create(r.Context(), &session); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to create session")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, session)
+}
+
+// GetSession returns a single session the authenticated user may view
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.viewableSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, session)
+}
+
+// UpdateSession updates a session owned by the authenticated user
+func (h *Handler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req SessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if err := req.validate(); err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	session.Name = req.Name
+	session.OpponentName = req.OpponentName
+	session.SessionDate = req.SessionDate
+
+	if !h.resolveOpponent(w, r, session, req.OpponentID) {
+		return
+	}
+
+	if err := h.Sessions.Update(r.Context(), session); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to update session")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, session)
+}
+
+// DeleteSession deletes a session owned by the authenticated user
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.Sessions.Delete(r.Context(), session.ID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to delete session")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Session deleted successfully",
+	})
+}
+
+// ownedSession loads the session named by the given URL parameter and checks
+// that it belongs to the authenticated user. Sessions owned by someone else
+// are reported as not found so their existence is not revealed. When ok is
+// false an error response has already been written.
+func (h *Handler) ownedSession(w http.ResponseWriter, r *http.Request, param string) (session *models.Session, ok bool) {
+	return h.authorizedSession(w, r, param, false)
+}
+
+// viewableSession is like ownedSession but also allows read access for the
+// player's coaches and for admins
+func (h *Handler) viewableSession(w http.ResponseWriter, r *http.Request, param string) (session *models.Session, ok bool) {
+	return h.authorizedSession(w, r, param, true)
+}
+
+// authorizedSession implements ownedSession and viewableSession
+func (h *Handler) authorizedSession(w http.ResponseWriter, r *http.Request, param string, view bool) (session *models.Session, ok bool) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return nil, false
+	}
+
+	id, err := URLParamInt(r, param)
+	if err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid session ID"))
+		return nil, false
+	}
+
+	session, err = h.Sessions.GetByID(r.Context(), id)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Session not found", "Failed to retrieve session")
+		return nil, false
+	}
+
+	if session.UserID == userID {
+		return session, true
+	}
+
+	if view {
+		allowed, err := h.canView(r.Context(), userID, session.UserID)
+		if err != nil {
+			RespondWithServiceError(w, r, err, "Failed to retrieve session")
+			return nil, false
+		}
+		if allowed {
+			return session, true
+		}
+	}
+
+	RespondWithError(w, r, apperr.NotFound("Session not found"))
+	return nil, false
+}
+
+// viewedPlayer returns the user whose data a request reads: the player given
+// by the player_id query parameter if the authenticated user may view them,
+// and otherwise the authenticated user. When ok is false an error response
+// has already been written.
+func (h *Handler) viewedPlayer(w http.ResponseWriter, r *http.Request) (playerID int, ok bool) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return 0, false
+	}
+
+	value := r.URL.Query().Get("player_id")
+	if value == "" {
+		return userID, true
+	}
+
+	playerID, err = strconv.Atoi(value)
+	if err != nil || playerID <= 0 {
+		RespondWithError(w, r, apperr.Invalid("player_id", "Invalid player ID"))
+		return 0, false
+	}
+
+	allowed, err := h.canView(r.Context(), userID, playerID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to check access")
+		return 0, false
+	}
+	if !allowed {
+		RespondWithError(w, r, apperr.NotFound("Player not found"))
+		return 0, false
+	}
+
+	return playerID, true
+}
+
+// canView reports whether a user may view the data of a player other than
+// themselves
+func (h *Handler) canView(ctx context.Context, userID, playerID int) (bool, error) {
+	return h.Coaches.CanView(ctx, userID, playerID)
+}
+
+// resolveOpponent links the session to an opponent of its owner: the one with
+// the given ID if set, otherwise the one matching the session's opponent name,
+// which is created on first use. When ok is false an error response has
+// already been written.
+func (h *Handler) resolveOpponent(w http.ResponseWriter, r *http.Request, session *models.Session, opponentID *int) (ok bool) {
+	if opponentID != nil {
+		opponent, err := h.Opponents.GetByID(r.Context(), *opponentID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			RespondWithServiceError(w, r, err, "Failed to retrieve opponent")
+			return false
+		}
+		if err != nil || opponent.UserID != session.UserID {
+			RespondWithError(w, r, apperr.Invalid("opponent_id", "Opponent not found"))
+			return false
+		}
+		session.OpponentID = &opponent.ID
+		session.OpponentName = opponent.Name
+		return true
+	}
+
+	if session.OpponentName == "" {
+		session.OpponentID = nil
+		return true
+	}
+
+	opponent, err := h.Opponents.FindOrCreate(r.Context(), session.UserID, session.OpponentName)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to save opponent")
+		return false
+	}
+	session.OpponentID = &opponent.ID
+	session.OpponentName = opponent.Name
+	return true
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// ShareRequest represents the optional create share link request body
+type ShareRequest struct {
+	ExpiresInHours int `json:"expires_in_hours,omitempty"`
+}
+
+// SharedSessionView is the read-only view of a session served to share link
+// recipients. It deliberately omits the owner, the opponent, who never agreed
+// to be published, and all internal IDs.
+type SharedSessionView struct {
+	Name        string            `json:"name"`
+	SessionDate time.Time         `json:"session_date"`
+	ErrorCount  int               `json:"error_count"`
+	Errors      []SharedErrorView `json:"errors"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+
+	ErrorsByStroke  map[string]int `json:"errors_by_stroke,omitempty"`
+	ErrorsBySide    map[string]int `json:"errors_by_side,omitempty"`
+	ErrorsByOutcome map[string]int `json:"errors_by_outcome,omitempty"`
+}
+
+// SharedErrorView is the read-only view of an error entry in a shared session
+type SharedErrorView struct {
+	Count      int       `json:"count"`
+	Stroke     string    `json:"stroke,omitempty"`
+	Side       string    `json:"side,omitempty"`
+	Outcome    string    `json:"outcome,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ShareSession creates a share link for a session owned by the authenticated user
+func (h *Handler) ShareSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	// The body is optional; an empty one uses the default expiry
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	expiry := time.Duration(req.ExpiresInHours) * time.Hour
+	if req.ExpiresInHours < 0 || expiry > models.MaxShareExpiry {
+		RespondWithError(w, r, apperr.Invalid("expires_in_hours", "Expiry must be between 1 and 2160 hours"))
+		return
+	}
+
+	link := models.SharedLink{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+	}
+
+	if err := h.Shares.Create(r.Context(), &link, expiry); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to create share link")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, link)
+}
+
+// GetShares lists the active share links of a session owned by the authenticated user
+func (h *Handler) GetShares(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	links, err := h.Shares.GetActiveBySessionID(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve share links")
+		return
+	}
+	if links == nil {
+		links = []models.SharedLink{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, links)
+}
+
+// RemoveShare revokes share links of a session owned by the authenticated user.
+// With a token query parameter only that link is revoked, otherwise all of them are.
+func (h *Handler) RemoveShare(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.ownedSession(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if token := r.URL.Query().Get("token"); token != "" {
+		found, err := h.Shares.Revoke(r.Context(), session.ID, token)
+		if err != nil {
+			RespondWithServiceError(w, r, err, "Failed to revoke share link")
+			return
+		}
+		if !found {
+			RespondWithError(w, r, apperr.NotFound("Share link not found"))
+			return
+		}
+	} else if _, err := h.Shares.RevokeAll(r.Context(), session.ID); err != nil {
+		RespondWithServiceError(w, r, err, "Failed to revoke share links")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SuccessResponse{
+		Message: "Share link revoked successfully",
+	})
+}
+
+// GetSharedSession serves the read-only view of a shared session to anyone holding the token
+func (h *Handler) GetSharedSession(w http.ResponseWriter, r *http.Request) {
+	link, err := h.Shares.GetByToken(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Shared session not found", "Failed to retrieve shared session")
+		return
+	}
+
+	if link.Expired() {
+		RespondWithError(w, r, apperr.New(apperr.CodeGone, "This share link has expired"))
+		return
+	}
+
+	session, err := h.Sessions.GetByID(r.Context(), link.SessionID)
+	if err != nil {
+		RespondWithLookupError(w, r, err, "Shared session not found", "Failed to retrieve shared session")
+		return
+	}
+
+	entries, err := h.Errors.GetBySessionID(r.Context(), session.ID)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve shared session")
+		return
+	}
+
+	view := SharedSessionView{
+		Name:        session.Name,
+		SessionDate: session.SessionDate,
+		ErrorCount:  session.ErrorCount,
+		Errors:      make([]SharedErrorView, 0, len(entries)),
+		ExpiresAt:   link.ExpiresAt,
+
+		ErrorsByStroke:  session.ErrorsByStroke,
+		ErrorsBySide:    session.ErrorsBySide,
+		ErrorsByOutcome: session.ErrorsByOutcome,
+	}
+	for _, entry := range entries {
+		view.Errors = append(view.Errors, SharedErrorView{
+			Count:      entry.Count,
+			Stroke:     entry.Stroke,
+			Side:       entry.Side,
+			Outcome:    entry.Outcome,
+			RecordedAt: entry.RecordedAt,
+		})
+	}
+
+	RespondWithJSON(w, http.StatusOK, view)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/stats"
+)
+
+// GetStats returns error totals, averages, rolling averages and streaks for
+// the authenticated user, or for a player they coach given by player_id
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
+	totals, ok := h.sessionTotals(w, r)
+	if !ok {
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, stats.Summarize(totals))
+}
+
+// GetStatsTrend returns every session of the authenticated user, or of a
+// player they coach, with its rolling averages
+func (h *Handler) GetStatsTrend(w http.ResponseWriter, r *http.Request) {
+	totals, ok := h.sessionTotals(w, r)
+	if !ok {
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, stats.Trend(totals))
+}
+
+// sessionTotals loads the session totals of the viewed player matching the
+// filter in the query string. When ok is false an error response has already
+// been written.
+func (h *Handler) sessionTotals(w http.ResponseWriter, r *http.Request) (totals []models.SessionTotal, ok bool) {
+	userID, ok := h.viewedPlayer(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	filter, err := h.parseSessionFilter(r)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return nil, false
+	}
+
+	totals, err = h.Sessions.GetTotalsByUserID(r.Context(), userID, filter)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve statistics")
+		return nil, false
+	}
+
+	return totals, true
+}
+
+// parseSessionFilter reads the from, to, opponent, opponent_id, type,
+// min_errors and max_errors query parameters. Dates may be given as
+// YYYY-MM-DD or RFC 3339; a date-only "to" includes that day. The type is
+// told by the points logged, so it cannot be filtered on in memory.
+func (h *Handler) parseSessionFilter(r *http.Request) (models.SessionFilter, error) {
+	var filter models.SessionFilter
+	q := r.URL.Query()
+
+	if v := q.Get("from"); v != "" {
+		from, _, err := parseDate(v)
+		if err != nil {
+			return filter, apperr.Invalid("from", "Invalid from date")
+		}
+		filter.From = from
+	}
+
+	if v := q.Get("to"); v != "" {
+		to, dateOnly, err := parseDate(v)
+		if err != nil {
+			return filter, apperr.Invalid("to", "Invalid to date")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+
+	filter.Opponent = strings.TrimSpace(q.Get("opponent"))
+
+	if v := q.Get("opponent_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return filter, apperr.Invalid("opponent_id", "Invalid opponent_id")
+		}
+		filter.OpponentID = id
+	}
+
+	switch v := q.Get("type"); v {
+	case "":
+	case models.SessionTypeMatch, models.SessionTypePractice:
+		if h.InMemory {
+			return filter, apperr.New(apperr.CodeNotImplemented, "Filtering by type is not available without a database")
+		}
+		filter.Type = v
+	default:
+		return filter, apperr.Invalid("type", "Type must be match or practice")
+	}
+
+	for _, bound := range []struct {
+		name string
+		dst  **int
+	}{
+		{"min_errors", &filter.MinErrors},
+		{"max_errors", &filter.MaxErrors},
+	} {
+		if v := q.Get(bound.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return filter, apperr.Invalid(bound.name, "Invalid "+bound.name)
+			}
+			*bound.dst = &n
+		}
+	}
+	if filter.MinErrors != nil && filter.MaxErrors != nil && *filter.MinErrors > *filter.MaxErrors {
+		return filter, apperr.Invalid("min_errors", "min_errors must not exceed max_errors")
+	}
+
+	return filter, nil
+}
+
+// parseDate parses a YYYY-MM-DD or RFC 3339 date and reports whether it was date-only
+func parseDate(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, v)
+	return t, false, err
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// SyncRequest represents a batch of offline changes
+type SyncRequest struct {
+	Mutations []models.SyncMutation `json:"mutations"`
+}
+
+// SyncResponse reports the outcome of every mutation, in request order
+type SyncResponse struct {
+	Results []models.SyncResult `json:"results"`
+	Cursor  string              `json:"cursor"` // Change feed cursor after the batch
+}
+
+// SyncChanges applies a batch of session and error mutations made by the client,
+// possibly while offline
+func (h *Handler) SyncChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req SyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+	if len(req.Mutations) > models.MaxSyncMutations {
+		RespondWithError(w, r, apperr.New(apperr.CodeTooLarge, fmt.Sprintf("At most %d mutations can be synced at once", models.MaxSyncMutations)))
+		return
+	}
+
+	results, cursor, err := h.Sync.Apply(r.Context(), userID, req.Mutations)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to sync changes")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, SyncResponse{
+		Results: results,
+		Cursor:  cursor,
+	})
+}
+
+// ChangesResponse is a page of the change feed
+type ChangesResponse struct {
+	Changes []models.Change `json:"changes"`
+	Cursor  string          `json:"cursor"`   // Pass as since to get the next page
+	HasMore bool            `json:"has_more"` // More changes are waiting
+}
+
+// GetChanges returns what changed for the authenticated user since the given
+// cursor, including tombstones of deleted rows. Without a cursor every row is
+// returned, so a client can build its cache from scratch.
+func (h *Handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	since, err := models.ParseChangeCursor(r.URL.Query().Get("since"))
+	if err != nil {
+		RespondWithError(w, r, apperr.Invalid("since", "Invalid since cursor"))
+		return
+	}
+
+	limit := models.DefaultChangesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > models.MaxChangesLimit {
+			RespondWithError(w, r, apperr.Invalid("limit", fmt.Sprintf("Limit must be between 1 and %d", models.MaxChangesLimit)))
+			return
+		}
+	}
+
+	changes, cursor, more, err := h.Changes.Since(r.Context(), userID, since, limit)
+	if err != nil {
+		RespondWithServiceError(w, r, err, "Failed to retrieve changes")
+		return
+	}
+	if changes == nil {
+		changes = []models.Change{}
+	}
+
+	RespondWithJSON(w, http.StatusOK, ChangesResponse{
+		Changes: changes,
+		Cursor:  models.FormatChangeCursor(cursor),
+		HasMore: more,
+	})
+}
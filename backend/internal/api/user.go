@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// UpdateUserRequest represents the profile update request body. The role
+// can only be changed by an admin.
+type UpdateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// GetUser returns the profile of the authenticated user
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	user, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, r, apperr.NotFound("User not found"))
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, user)
+}
+
+// UpdateUser updates the profile of the authenticated user
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r)
+	if err != nil {
+		RespondWithError(w, r, apperr.Unauthorized("Unauthorized"))
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondWithError(w, r, apperr.BadRequest("Invalid request payload"))
+		return
+	}
+
+	user, err := h.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		RespondWithError(w, r, apperr.NotFound("User not found"))
+		return
+	}
+
+	// Only overwrite the fields that were provided
+	if name := strings.TrimSpace(req.Name); name != "" {
+		if err := models.ValidateName(name); err != nil {
+			RespondWithError(w, r, apperr.Invalid("name", "Name must be at most 255 characters without control characters"))
+			return
+		}
+		user.Name = name
+	}
+	if email := models.NormalizeEmail(req.Email); email != "" {
+		if err := models.ValidateEmail(email); err != nil {
+			RespondWithError(w, r, apperr.Invalid("email", "Invalid email address"))
+			return
+		}
+		user.Email = email
+	}
+
+	if err := h.Users.Update(r.Context(), user); err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			RespondWithError(w, r, apperr.Conflict("An account with that email already exists"))
+			return
+		}
+		RespondWithServiceError(w, r, err, "Failed to update user")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, user)
+}
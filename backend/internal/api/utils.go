@@ -1,14 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-)
+	"strconv"
 
-// ErrorResponse represents an error message
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+)
 
 // SuccessResponse represents a success message
 type SuccessResponse struct {
@@ -16,9 +19,10 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// RespondWithError sends an error response with the specified status code
-func RespondWithError(w http.ResponseWriter, code int, message string) {
-	RespondWithJSON(w, code, ErrorResponse{Error: message})
+// RespondWithError sends err as RFC 7807 problem details. Errors that are not
+// an *apperr.Error are reported as internal errors.
+func RespondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	apperr.Write(w, r, err)
 }
 
 // RespondWithJSON sends a JSON response with the specified status code
@@ -35,3 +39,37 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.WriteHeader(code)
 	w.Write(response)
 }
+
+// RespondWithServiceError sends the response for an error returned by a
+// service: 504 if the database did not answer before the deadline, 404 if the
+// row does not exist, and 500 with the given message otherwise
+func RespondWithServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	RespondWithLookupError(w, r, err, "Not found", message)
+}
+
+// RespondWithLookupError is like RespondWithServiceError with the message to
+// send when the row looked up does not exist
+func RespondWithLookupError(w http.ResponseWriter, r *http.Request, err error, notFound, message string) {
+	switch {
+	case isTimeout(err):
+		RespondWithError(w, r, apperr.Wrap(err, apperr.CodeTimeout, "The request timed out"))
+	case errors.Is(err, pgx.ErrNoRows):
+		RespondWithError(w, r, apperr.NotFound(notFound))
+	default:
+		RespondWithError(w, r, apperr.Internal(err, message))
+	}
+}
+
+// isTimeout reports whether a query failed because its deadline passed
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err)
+}
+
+// URLParamInt parses a positive integer URL parameter such as a resource ID
+func URLParamInt(r *http.Request, name string) (int, error) {
+	value, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || value <= 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	return value, nil
+}
@@ -0,0 +1,227 @@
+// Package apperr defines the errors the API reports to clients and renders
+// them as RFC 7807 problem details
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Code identifies the kind of an error independently of its message
+type Code string
+
+// Error codes
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodeGone             Code = "gone"
+	CodeTooLarge         Code = "payload_too_large"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal"
+	CodeNotImplemented   Code = "not_implemented"
+	CodeTimeout          Code = "timeout"
+)
+
+// statuses maps each code to its HTTP status
+var statuses = map[Code]int{
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeValidationFailed: http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	CodeConflict:         http.StatusConflict,
+	CodeGone:             http.StatusGone,
+	CodeTooLarge:         http.StatusRequestEntityTooLarge,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeNotImplemented:   http.StatusNotImplemented,
+	CodeTimeout:          http.StatusGatewayTimeout,
+}
+
+// Status returns the HTTP status of a code
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldError describes an invalid field of a request body or query string
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error meant for clients. Message is shown to them; the wrapped
+// error is only logged.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	// Extensions are added to the problem details as extra members
+	Extensions map[string]interface{}
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status of the error
+func (e *Error) Status() int {
+	return e.Code.Status()
+}
+
+// New returns an error with the given code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap returns an error with the given code and message caused by err
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// BadRequest reports a malformed request, such as invalid JSON or IDs
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+// Invalid reports an invalid field. The message names the problem on its own,
+// as clients may show it without the field.
+func Invalid(field, message string) *Error {
+	return &Error{
+		Code:    CodeValidationFailed,
+		Message: message,
+		Fields:  []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Unauthorized reports a missing or invalid credential
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+// Forbidden reports an action the user may not take
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+// NotFound reports a resource that does not exist or is hidden from the user
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+// Conflict reports a request that conflicts with the current state
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// Internal reports a failure on the server caused by err
+func Internal(err error, message string) *Error {
+	return Wrap(err, CodeInternal, message)
+}
+
+// From returns err as an *Error, treating errors of other types as internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err, "Internal server error")
+}
+
+// Problem is an RFC 7807 problem details object. Code, RequestID and Errors
+// are extension members.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      Code         `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// ContentType is the media type of problem details
+const ContentType = "application/problem+json"
+
+// Write sends err as problem details. Errors that are not an *Error are sent
+// as internal errors without revealing their message. Server errors are
+// logged with their cause and request ID.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := From(err)
+	status := appErr.Status()
+	requestID := middleware.GetReqID(r.Context())
+
+	if status >= http.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %v", requestID, r.Method, r.URL.Path, appErr)
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  r.URL.Path,
+		Code:      appErr.Code,
+		RequestID: requestID,
+		Errors:    appErr.Fields,
+	}
+
+	body, err := marshalProblem(problem, appErr.Extensions)
+	if err != nil {
+		log.Printf("[%s] Failed to marshal problem details: %v", requestID, err)
+		body = []byte(`{"type":"about:blank","title":"Internal Server Error","status":500,"code":"internal"}`)
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// marshalProblem encodes the problem with the extension members added
+func marshalProblem(problem Problem, extensions map[string]interface{}) ([]byte, error) {
+	if len(extensions) == 0 {
+		return json.Marshal(problem)
+	}
+
+	members := make(map[string]interface{}, len(extensions)+8)
+	for name, value := range extensions {
+		members[name] = value
+	}
+
+	// The standard members take precedence over extensions of the same name
+	data, err := json.Marshal(problem)
+	if err != nil {
+		return nil, err
+	}
+	var standard map[string]json.RawMessage
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return nil, err
+	}
+	for name, value := range standard {
+		members[name] = value
+	}
+
+	return json.Marshal(members)
+}
@@ -0,0 +1,2 @@
+-- Remove last login tracking
+ALTER TABLE users DROP COLUMN IF EXISTS last_login_at;
@@ -0,0 +1,2 @@
+-- Track when each user last logged in
+ALTER TABLE users ADD COLUMN last_login_at TIMESTAMP WITH TIME ZONE;
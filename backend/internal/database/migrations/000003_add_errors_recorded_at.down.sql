@@ -0,0 +1,4 @@
+-- Remove per-entry error timestamps
+DROP INDEX IF EXISTS idx_errors_session_id_recorded_at;
+ALTER TABLE errors DROP CONSTRAINT IF EXISTS errors_count_non_negative;
+ALTER TABLE errors DROP COLUMN IF EXISTS recorded_at;
@@ -0,0 +1,6 @@
+-- Give each error entry its own timestamp and forbid negative counts
+ALTER TABLE errors ADD COLUMN recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+UPDATE errors SET recorded_at = created_at WHERE created_at IS NOT NULL;
+ALTER TABLE errors ADD CONSTRAINT errors_count_non_negative CHECK (count >= 0);
+
+CREATE INDEX idx_errors_session_id_recorded_at ON errors(session_id, recorded_at);
@@ -0,0 +1,8 @@
+-- Remove error categories
+ALTER TABLE errors DROP COLUMN IF EXISTS outcome;
+ALTER TABLE errors DROP COLUMN IF EXISTS side;
+ALTER TABLE errors DROP COLUMN IF EXISTS stroke;
+
+DROP TABLE IF EXISTS error_outcomes;
+DROP TABLE IF EXISTS error_sides;
+DROP TABLE IF EXISTS error_strokes;
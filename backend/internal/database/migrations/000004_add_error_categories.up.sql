@@ -0,0 +1,41 @@
+-- Categorize unforced errors by stroke, side (wing) and outcome
+
+-- Create lookup tables
+CREATE TABLE error_strokes (
+    code VARCHAR(32) PRIMARY KEY,
+    label VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE error_sides (
+    code VARCHAR(32) PRIMARY KEY,
+    label VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE error_outcomes (
+    code VARCHAR(32) PRIMARY KEY,
+    label VARCHAR(64) NOT NULL
+);
+
+INSERT INTO error_strokes (code, label) VALUES
+    ('forehand', 'Forehand'),
+    ('backhand', 'Backhand'),
+    ('serve', 'Serve'),
+    ('volley', 'Volley'),
+    ('overhead', 'Overhead'),
+    ('return', 'Return'),
+    ('drop_shot', 'Drop shot');
+
+INSERT INTO error_sides (code, label) VALUES
+    ('forehand', 'Forehand side'),
+    ('backhand', 'Backhand side');
+
+INSERT INTO error_outcomes (code, label) VALUES
+    ('net', 'Into the net'),
+    ('long', 'Long'),
+    ('wide', 'Wide'),
+    ('double_fault', 'Double fault');
+
+-- Existing entries stay uncategorized
+ALTER TABLE errors ADD COLUMN stroke VARCHAR(32) REFERENCES error_strokes(code);
+ALTER TABLE errors ADD COLUMN side VARCHAR(32) REFERENCES error_sides(code);
+ALTER TABLE errors ADD COLUMN outcome VARCHAR(32) REFERENCES error_outcomes(code);
@@ -0,0 +1,3 @@
+-- Remove point-by-point match logging
+DROP TABLE IF EXISTS points;
+DROP TABLE IF EXISTS match_formats;
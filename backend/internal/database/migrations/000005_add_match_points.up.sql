@@ -0,0 +1,26 @@
+-- Point-by-point match logging
+
+-- Create match formats table (one per session; sessions without a row use the default format)
+CREATE TABLE match_formats (
+    session_id INTEGER PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+    best_of SMALLINT NOT NULL DEFAULT 3 CHECK (best_of IN (1, 3, 5)),
+    no_ad BOOLEAN NOT NULL DEFAULT FALSE,
+    match_tiebreak BOOLEAN NOT NULL DEFAULT FALSE,
+    first_server VARCHAR(16) NOT NULL DEFAULT 'player' CHECK (first_server IN ('player', 'opponent')),
+    games_per_set SMALLINT NOT NULL DEFAULT 6,
+    tiebreak_points SMALLINT NOT NULL DEFAULT 7,
+    match_tb_points SMALLINT NOT NULL DEFAULT 10,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create points table
+CREATE TABLE points (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    winner VARCHAR(16) NOT NULL CHECK (winner IN ('player', 'opponent')),
+    ending VARCHAR(32) NOT NULL CHECK (ending IN ('winner', 'unforced_error', 'forced_error', 'ace', 'double_fault')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT points_session_id_seq_key UNIQUE (session_id, seq)
+);
@@ -0,0 +1,6 @@
+-- Remove first-class opponents; sessions keep their opponent_name
+DROP INDEX IF EXISTS idx_sessions_opponent_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS opponent_id;
+
+DROP INDEX IF EXISTS idx_opponents_user_id_lower_name;
+DROP TABLE IF EXISTS opponents;
@@ -0,0 +1,28 @@
+-- First-class opponents, matched case-insensitively per user
+
+-- Create opponents table
+CREATE TABLE opponents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_opponents_user_id_lower_name ON opponents(user_id, LOWER(name));
+
+-- Link sessions to opponents; opponent_name is kept as the display name
+ALTER TABLE sessions ADD COLUMN opponent_id INTEGER REFERENCES opponents(id) ON DELETE SET NULL;
+CREATE INDEX idx_sessions_opponent_id ON sessions(opponent_id);
+
+-- Backfill opponents from the free-text names, keeping the earliest spelling
+INSERT INTO opponents (user_id, name)
+SELECT DISTINCT ON (user_id, LOWER(TRIM(opponent_name))) user_id, TRIM(opponent_name)
+FROM sessions
+WHERE user_id IS NOT NULL AND opponent_name IS NOT NULL AND TRIM(opponent_name) <> ''
+ORDER BY user_id, LOWER(TRIM(opponent_name)), created_at;
+
+UPDATE sessions s
+SET opponent_id = o.id, opponent_name = o.name
+FROM opponents o
+WHERE o.user_id = s.user_id AND LOWER(o.name) = LOWER(TRIM(s.opponent_name));
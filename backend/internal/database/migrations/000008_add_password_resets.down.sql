@@ -0,0 +1,5 @@
+-- Remove password reset tokens
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_valid_after;
+
+DROP INDEX IF EXISTS idx_password_resets_user_id;
+DROP TABLE IF EXISTS password_resets;
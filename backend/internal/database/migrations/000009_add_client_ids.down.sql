@@ -0,0 +1,6 @@
+-- Remove client-generated IDs
+DROP INDEX IF EXISTS idx_errors_client_id;
+ALTER TABLE errors DROP COLUMN IF EXISTS client_id;
+
+DROP INDEX IF EXISTS idx_sessions_user_id_client_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS client_id;
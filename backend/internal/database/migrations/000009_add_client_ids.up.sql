@@ -0,0 +1,9 @@
+-- Client-generated IDs for offline sync
+
+-- Sessions and errors created offline are identified by a UUID chosen by the
+-- client, so replaying a sync batch never creates duplicates
+ALTER TABLE sessions ADD COLUMN client_id UUID;
+CREATE UNIQUE INDEX idx_sessions_user_id_client_id ON sessions(user_id, client_id);
+
+ALTER TABLE errors ADD COLUMN client_id UUID;
+CREATE UNIQUE INDEX idx_errors_client_id ON errors(client_id);
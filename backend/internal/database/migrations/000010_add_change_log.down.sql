@@ -0,0 +1,6 @@
+-- Remove the change log
+DROP INDEX IF EXISTS idx_changes_user_id_client_id;
+DROP INDEX IF EXISTS idx_changes_user_id_seq;
+DROP TABLE IF EXISTS changes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS change_seq;
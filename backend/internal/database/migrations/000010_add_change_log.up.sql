@@ -0,0 +1,48 @@
+-- Per-user change log for incremental client sync
+
+-- Every change of a user bumps their sequence number. Writers lock the user
+-- row, so changes of a user commit in sequence order.
+ALTER TABLE users ADD COLUMN change_seq BIGINT NOT NULL DEFAULT 0;
+
+-- Create changes table. It keeps only the latest change of each row, and rows
+-- that were deleted keep a tombstone.
+CREATE TABLE changes (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    client_id UUID,
+    seq BIGINT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, entity, entity_id)
+);
+
+CREATE UNIQUE INDEX idx_changes_user_id_seq ON changes(user_id, seq);
+
+-- Sync checks tombstones by client ID so stale edits don't resurrect rows
+CREATE INDEX idx_changes_user_id_client_id ON changes(user_id, client_id) WHERE deleted;
+
+-- Backfill the log with the existing rows so a full sync starts from zero
+INSERT INTO changes (user_id, entity, entity_id, client_id, seq, changed_at)
+SELECT user_id, entity, entity_id, client_id,
+       ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY changed_at, entity, entity_id),
+       changed_at
+FROM (
+    SELECT user_id, 'session' AS entity, id AS entity_id, client_id, COALESCE(updated_at, NOW()) AS changed_at
+    FROM sessions
+    WHERE user_id IS NOT NULL
+    UNION ALL
+    SELECT s.user_id, 'error', e.id, e.client_id, COALESCE(e.updated_at, NOW())
+    FROM errors e
+    JOIN sessions s ON s.id = e.session_id
+    WHERE s.user_id IS NOT NULL
+    UNION ALL
+    SELECT user_id, 'share', id, NULL, COALESCE(created_at, NOW())
+    FROM shared_links
+    WHERE user_id IS NOT NULL
+) existing;
+
+UPDATE users u
+SET change_seq = c.max_seq
+FROM (SELECT user_id, MAX(seq) AS max_seq FROM changes GROUP BY user_id) c
+WHERE c.user_id = u.id;
@@ -0,0 +1,6 @@
+-- Remove coach links and user roles
+DROP INDEX IF EXISTS idx_coach_links_player_id;
+DROP TABLE IF EXISTS coach_links;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
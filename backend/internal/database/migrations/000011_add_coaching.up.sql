@@ -0,0 +1,22 @@
+-- User roles and coach-player links
+
+-- Every user is a player unless they sign up as a coach; admins are set by hand
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'player';
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('player', 'coach', 'admin'));
+
+-- Create coach links table. Either side can invite; the link grants the coach
+-- read access to the player's data once the other side accepts.
+CREATE TABLE coach_links (
+    id SERIAL PRIMARY KEY,
+    coach_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invited_by INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT coach_links_coach_id_player_id_key UNIQUE (coach_id, player_id),
+    CONSTRAINT coach_links_status_check CHECK (status IN ('pending', 'accepted')),
+    CONSTRAINT coach_links_not_self CHECK (coach_id <> player_id)
+);
+
+CREATE INDEX idx_coach_links_player_id ON coach_links(player_id);
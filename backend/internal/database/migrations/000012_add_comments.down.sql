@@ -0,0 +1,4 @@
+-- Remove comments
+DROP INDEX IF EXISTS idx_comments_parent_id;
+DROP INDEX IF EXISTS idx_comments_session_id;
+DROP TABLE IF EXISTS comments;
@@ -0,0 +1,18 @@
+-- Comments on sessions and error entries
+
+-- Create comments table. Every comment belongs to a session; comments about a
+-- single error entry also name it. Replies point at their parent and are
+-- deleted along with it.
+CREATE TABLE comments (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    error_id INTEGER REFERENCES errors(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_comments_session_id ON comments(session_id);
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
@@ -0,0 +1,5 @@
+-- Remove rate limits and lockouts
+DROP INDEX IF EXISTS idx_login_failures_last_failed_at;
+DROP TABLE IF EXISTS login_failures;
+DROP INDEX IF EXISTS idx_rate_limit_buckets_full_at;
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,21 @@
+-- Rate limits and lockouts for the auth endpoints
+
+-- Create rate_limit_buckets table. Each row is a token bucket that will be
+-- full again at full_at; full buckets are deleted.
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    full_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
+
+-- Create login_failures table counting consecutive failed logins per key and
+-- how long the key is locked out for them
+CREATE TABLE login_failures (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_login_failures_last_failed_at ON login_failures(last_failed_at);
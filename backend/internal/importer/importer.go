@@ -0,0 +1,322 @@
+// Package importer reads historical sessions and error counts from CSV files
+// such as spreadsheets exported by players
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Fields that can be mapped to CSV columns
+const (
+	FieldDate     = "date"
+	FieldSession  = "session"
+	FieldOpponent = "opponent"
+	FieldErrors   = "errors"
+	FieldStroke   = "stroke"
+	FieldSide     = "side"
+	FieldOutcome  = "outcome"
+)
+
+// Fields lists the mappable fields
+var Fields = []string{FieldDate, FieldSession, FieldOpponent, FieldErrors, FieldStroke, FieldSide, FieldOutcome}
+
+// DefaultDateFormat is the date layout expected unless the mapping sets another
+const DefaultDateFormat = "2006-01-02"
+
+// DefaultSessionName names sessions imported without a session name
+const DefaultSessionName = "Imported session"
+
+// MaxLineErrors is how many invalid rows are reported before giving up
+const MaxLineErrors = 100
+
+// MaxErrorCount is the largest error count of a row. It is far above any real
+// session and keeps counts within the INTEGER column.
+const MaxErrorCount = 100000
+
+// Mapping names the CSV column holding each field. Date and errors columns
+// are required; the others are optional and skipped when the file does not
+// have them, unless they were set explicitly.
+type Mapping struct {
+	columns  map[string]string
+	explicit map[string]bool
+	// DateFormat is the Go time layout of the date column
+	DateFormat string
+}
+
+// DefaultMapping maps every field to the column of the same name
+func DefaultMapping() *Mapping {
+	m := &Mapping{
+		columns:    make(map[string]string),
+		explicit:   make(map[string]bool),
+		DateFormat: DefaultDateFormat,
+	}
+	for _, field := range Fields {
+		m.columns[field] = field
+	}
+	return m
+}
+
+// Set maps a field to a column header
+func (m *Mapping) Set(field, column string) error {
+	if _, ok := m.columns[field]; !ok {
+		return fmt.Errorf("unknown field %q, use one of %s", field, strings.Join(Fields, ", "))
+	}
+	column = strings.TrimSpace(column)
+	if column == "" {
+		return fmt.Errorf("column for %s must not be empty", field)
+	}
+	m.columns[field] = column
+	m.explicit[field] = true
+	return nil
+}
+
+// Parse reads a comma-separated list of field=column pairs such as
+// "date=Day,errors=Unforced errors" into the mapping
+func (m *Mapping) Parse(spec string) error {
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		if err := m.Set(strings.ToLower(strings.TrimSpace(field)), column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LineError reports an invalid row of the file
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Read parses a CSV file with a header row into sessions. Each row is one
+// error entry; rows sharing a date, session name and opponent belong to the
+// same session. A row with an error count of zero records a session without
+// errors. Every row is checked, and the invalid ones are returned as line
+// errors instead of sessions. The error is only set if the file itself cannot
+// be read.
+func Read(r io.Reader, m *Mapping) ([]models.SessionWithErrors, []LineError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	index, err := m.resolve(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		sessions   []models.SessionWithErrors
+		byKey      = make(map[string]int)
+		lineErrors []LineError
+	)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				lineErrors = append(lineErrors, LineError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				return nil, lineErrors, nil
+			}
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		session, entry, err := m.parseRow(record, index)
+		if err != nil {
+			lineErrors = append(lineErrors, LineError{Line: line, Message: err.Error()})
+			if len(lineErrors) >= MaxLineErrors {
+				lineErrors = append(lineErrors, LineError{Line: line, Message: "Too many errors, stopped reading"})
+				return nil, lineErrors, nil
+			}
+			continue
+		}
+
+		key := session.SessionDate.Format(time.RFC3339) + "\x00" + session.Name + "\x00" + strings.ToLower(session.OpponentName)
+		i, ok := byKey[key]
+		if !ok {
+			if len(sessions) == models.MaxImportSessions {
+				lineErrors = append(lineErrors, LineError{
+					Line:    line,
+					Message: fmt.Sprintf("An import can create at most %d sessions", models.MaxImportSessions),
+				})
+				return nil, lineErrors, nil
+			}
+			i = len(sessions)
+			byKey[key] = i
+			sessions = append(sessions, models.SessionWithErrors{Session: *session, Errors: []models.ErrorEntry{}})
+		}
+
+		if entry != nil {
+			s := &sessions[i]
+			s.Errors = append(s.Errors, *entry)
+			s.ErrorCount += entry.Count
+		}
+	}
+
+	if len(lineErrors) > 0 {
+		return nil, lineErrors, nil
+	}
+
+	return sessions, nil, nil
+}
+
+// resolve finds the column index of every mapped field in the header; fields
+// without a column get -1
+func (m *Mapping) resolve(header []string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Byte order mark written by spreadsheets
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	index := make(map[string]int, len(Fields))
+	var missing []string
+	for _, field := range Fields {
+		i, ok := positions[strings.ToLower(m.columns[field])]
+		if !ok {
+			required := field == FieldDate || field == FieldErrors
+			if required || m.explicit[field] {
+				missing = append(missing, fmt.Sprintf("%q (%s)", m.columns[field], field))
+			}
+			i = -1
+		}
+		index[field] = i
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns %s", strings.Join(missing, ", "))
+	}
+	return index, nil
+}
+
+// parseRow validates a row and turns it into a session and, unless the error
+// count is zero, an error entry of that session
+func (m *Mapping) parseRow(record []string, index map[string]int) (*models.Session, *models.ErrorEntry, error) {
+	value := func(field string) string {
+		i := index[field]
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	date := value(FieldDate)
+	if date == "" {
+		return nil, nil, errors.New("Date is required")
+	}
+	sessionDate, err := time.ParseInLocation(m.DateFormat, date, time.UTC)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid date %q, expected the format %s", date, m.DateFormat)
+	}
+
+	name := value(FieldSession)
+	if name == "" {
+		name = DefaultSessionName
+	}
+	if err := models.ValidateName(name); err != nil {
+		return nil, nil, errors.New("Session name must be at most 255 characters without control characters")
+	}
+	if err := models.ValidateName(value(FieldOpponent)); err != nil {
+		return nil, nil, errors.New("Opponent name must be at most 255 characters without control characters")
+	}
+
+	session := &models.Session{
+		Name:         name,
+		OpponentName: models.NormalizeOpponentName(value(FieldOpponent)),
+		SessionDate:  sessionDate,
+	}
+
+	errorCount := value(FieldErrors)
+	if errorCount == "" {
+		return nil, nil, errors.New("Error count is required")
+	}
+	count, err := strconv.Atoi(errorCount)
+	if err != nil || count < 0 {
+		return nil, nil, fmt.Errorf("Invalid error count %q", errorCount)
+	}
+	if count > MaxErrorCount {
+		return nil, nil, fmt.Errorf("Error count must be at most %d", MaxErrorCount)
+	}
+
+	entry := &models.ErrorEntry{
+		Count:      count,
+		Stroke:     category(value(FieldStroke)),
+		Side:       category(value(FieldSide)),
+		Outcome:    category(value(FieldOutcome)),
+		RecordedAt: sessionDate,
+	}
+	if err := entry.ValidateCategories(); err != nil {
+		return nil, nil, err.Invalid()
+	}
+
+	if count == 0 {
+		if entry.Stroke != "" || entry.Side != "" || entry.Outcome != "" {
+			return nil, nil, errors.New("Categories require an error count of at least 1")
+		}
+		return session, nil, nil
+	}
+
+	return session, entry, nil
+}
+
+// category normalizes a category as typed in a spreadsheet, so "Drop shot"
+// becomes "drop_shot"
+func category(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), "_")
+}
+
+// isBlank reports whether every field of a record is empty
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Totals returns how many error entries, and how many errors in total, the
+// sessions hold
+func Totals(sessions []models.SessionWithErrors) (entries, total int) {
+	for _, session := range sessions {
+		entries += len(session.Errors)
+		total += session.ErrorCount
+	}
+	return entries, total
+}
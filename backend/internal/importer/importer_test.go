@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// describe renders sessions as "date name vs opponent: total [stroke/side/outcome:count ...]"
+// so whole imports can be compared at a glance
+func describe(sessions []models.SessionWithErrors) []string {
+	var lines []string
+	for _, s := range sessions {
+		var entries []string
+		for _, e := range s.Errors {
+			entries = append(entries, fmt.Sprintf("%s/%s/%s:%d", e.Stroke, e.Side, e.Outcome, e.Count))
+		}
+		lines = append(lines, fmt.Sprintf("%s %s vs %s: %d [%s]",
+			s.SessionDate.Format(time.RFC3339), s.Name, s.OpponentName, s.ErrorCount, strings.Join(entries, " ")))
+	}
+	return lines
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		csv      string
+		mapping  string
+		format   string
+		want     []string
+		wantLine []LineError
+	}{
+		{
+			name: "rows of one session are grouped",
+			csv: "date,session,opponent,errors,stroke,side,outcome\n" +
+				"2026-05-01,Practice,Wall,2,forehand,,net\n" +
+				"2026-05-01,Practice,wall,3,serve,,double_fault\n" +
+				"2026-05-02,Match,Alex,1,,,\n",
+			want: []string{
+				"2026-05-01T00:00:00Z Practice vs Wall: 5 [forehand/forehand/net:2 serve//double_fault:3]",
+				"2026-05-02T00:00:00Z Match vs Alex: 1 [//:1]",
+			},
+		},
+		{
+			name: "optional columns may be missing",
+			csv:  "Date,Errors\n2026-05-01,4\n",
+			want: []string{"2026-05-01T00:00:00Z Imported session vs : 4 [//:4]"},
+		},
+		{
+			name: "byte order mark before the header",
+			csv:  "\ufeffdate,errors\n2026-05-01,1\n",
+			want: []string{"2026-05-01T00:00:00Z Imported session vs : 1 [//:1]"},
+		},
+		{
+			name:    "mapped columns and date format",
+			csv:     "Day,Unforced errors,Shot\n01/05/2026,2,Drop  Shot\n",
+			mapping: "date=Day,errors=Unforced errors,stroke=Shot",
+			format:  "02/01/2006",
+			want:    []string{"2026-05-01T00:00:00Z Imported session vs : 2 [drop_shot//:2]"},
+		},
+		{
+			name: "zero count records a session without errors",
+			csv:  "date,session,errors\n2026-05-01,Rest day,0\n",
+			want: []string{"2026-05-01T00:00:00Z Rest day vs : 0 []"},
+		},
+		{
+			name: "invalid rows are reported by line, skipping blank lines",
+			csv: "date,errors,stroke\n" +
+				"\n" +
+				"2026-05-01,1,lob\n" +
+				",,\n" +
+				"May 1st,1,\n" +
+				"2026-05-01,0,serve\n" +
+				"2026-05-01,-1,\n" +
+				"2026-05-01,100001,\n" +
+				"2026-05-01,,\n" +
+				"2026-05-01,1,forehand\n",
+			wantLine: []LineError{
+				{Line: 3, Message: "Invalid stroke"},
+				{Line: 5, Message: `Invalid date "May 1st", expected the format 2006-01-02`},
+				{Line: 6, Message: "Categories require an error count of at least 1"},
+				{Line: 7, Message: `Invalid error count "-1"`},
+				{Line: 8, Message: "Error count must be at most 100000"},
+				{Line: 9, Message: "Error count is required"},
+			},
+		},
+		{
+			name: "quoted line breaks count towards line numbers",
+			csv:  "date,session,errors\n2026-05-01,\"Two\nlines\",1\n2026-05-01,Bad,x\n",
+			wantLine: []LineError{
+				{Line: 2, Message: "Session name must be at most 255 characters without control characters"},
+				{Line: 4, Message: `Invalid error count "x"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := DefaultMapping()
+			if tt.mapping != "" {
+				if err := m.Parse(tt.mapping); err != nil {
+					t.Fatalf("parse mapping: %v", err)
+				}
+			}
+			if tt.format != "" {
+				m.DateFormat = tt.format
+			}
+
+			sessions, lineErrors, err := Read(strings.NewReader(tt.csv), m)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if !reflect.DeepEqual(lineErrors, tt.wantLine) {
+				t.Errorf("line errors = %+v, want %+v", lineErrors, tt.wantLine)
+			}
+			if got := describe(sessions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		mapping string
+		want    string
+	}{
+		{name: "empty file", csv: "", want: "file is empty"},
+		{name: "missing required columns", csv: "day,count\n", want: `missing columns "date" (date), "errors" (errors)`},
+		{name: "missing mapped column", csv: "date,errors\n", mapping: "opponent=Rival", want: `missing columns "Rival" (opponent)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := DefaultMapping()
+			if tt.mapping != "" {
+				if err := m.Parse(tt.mapping); err != nil {
+					t.Fatalf("parse mapping: %v", err)
+				}
+			}
+			_, _, err := Read(strings.NewReader(tt.csv), m)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadStopsAfterTooManyErrors(t *testing.T) {
+	csv := "date,errors\n" + strings.Repeat("2026-05-01,x\n", MaxLineErrors+50)
+
+	sessions, lineErrors, err := Read(strings.NewReader(csv), DefaultMapping())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if sessions != nil {
+		t.Errorf("sessions = %d, want none", len(sessions))
+	}
+	if len(lineErrors) != MaxLineErrors+1 {
+		t.Fatalf("line errors = %d, want %d", len(lineErrors), MaxLineErrors+1)
+	}
+	last := lineErrors[len(lineErrors)-1]
+	if want := (LineError{Line: MaxLineErrors + 1, Message: "Too many errors, stopped reading"}); last != want {
+		t.Errorf("last line error = %+v, want %+v", last, want)
+	}
+}
+
+func TestReadCapsSessions(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	b.WriteString("date,errors\n")
+	for i := 0; i <= models.MaxImportSessions; i++ {
+		fmt.Fprintf(&b, "%s,1\n", start.AddDate(0, 0, i).Format(DefaultDateFormat))
+	}
+
+	sessions, lineErrors, err := Read(strings.NewReader(b.String()), DefaultMapping())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if sessions != nil {
+		t.Errorf("sessions = %d, want none", len(sessions))
+	}
+	want := []LineError{{
+		Line:    models.MaxImportSessions + 2,
+		Message: fmt.Sprintf("An import can create at most %d sessions", models.MaxImportSessions),
+	}}
+	if !reflect.DeepEqual(lineErrors, want) {
+		t.Errorf("line errors = %+v, want %+v", lineErrors, want)
+	}
+}
+
+func TestMappingParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "date=Day, errors = Count"},
+		{spec: "DATE=Day,,"},
+		{spec: "racket=Brand", wantErr: true},
+		{spec: "date", wantErr: true},
+		{spec: "date= ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			err := DefaultMapping().Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCategory(t *testing.T) {
+	tests := map[string]string{
+		"":              "",
+		"Forehand":      "forehand",
+		" Drop shot ":   "drop_shot",
+		"DOUBLE  FAULT": "double_fault",
+		"drop_shot":     "drop_shot",
+	}
+
+	for value, want := range tests {
+		if got := category(value); got != want {
+			t.Errorf("category(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestTotals(t *testing.T) {
+	sessions := []models.SessionWithErrors{
+		{Session: models.Session{ErrorCount: 5}, Errors: make([]models.ErrorEntry, 2)},
+		{Session: models.Session{ErrorCount: 0}, Errors: []models.ErrorEntry{}},
+		{Session: models.Session{ErrorCount: 1}, Errors: make([]models.ErrorEntry, 1)},
+	}
+
+	if entries, total := Totals(sessions); entries != 3 || total != 6 {
+		t.Errorf("Totals = %d entries, %d errors, want 3 and 6", entries, total)
+	}
+}
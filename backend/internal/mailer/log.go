@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// LogMailer writes messages to a writer instead of sending them. It is meant
+// for local development and tests, where the writer can be a file, stdout or
+// a buffer.
+type LogMailer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	out []Message
+}
+
+// NewLogMailer creates a LogMailer writing to w
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+// Send writes the message and remembers it
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.out = append(m.out, msg)
+	_, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\n\n%s\n---\n", msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// Sent returns the messages sent so far
+func (m *LogMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.out...)
+}
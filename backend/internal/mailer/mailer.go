@@ -0,0 +1,37 @@
+// Package mailer sends transactional email such as password reset links
+package mailer
+
+import (
+	"context"
+	"os"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns an SMTP mailer when an SMTP host is configured, and otherwise a
+// mailer that writes messages to stdout for local development
+func New(cfg config.SMTP) Mailer {
+	if cfg.Host == "" {
+		return NewLogMailer(os.Stdout)
+	}
+
+	return &SMTPMailer{
+		Host:     cfg.Host,
+		Port:     cfg.Port,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
+	}
+}
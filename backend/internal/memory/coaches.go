@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Coaches stores coach links in memory
+type Coaches struct {
+	s *Store
+}
+
+// GetByID retrieves a coach link by ID
+func (m *Coaches) GetByID(ctx context.Context, id int) (*models.CoachLink, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	link, ok := m.s.coachLinks[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return m.s.withNames(link), nil
+}
+
+// GetByCoachID retrieves the links of a coach to their players, newest first
+func (m *Coaches) GetByCoachID(ctx context.Context, coachID int) ([]models.CoachLink, error) {
+	return m.list(func(link *models.CoachLink) bool { return link.CoachID == coachID }), nil
+}
+
+// GetByPlayerID retrieves the links of a player to their coaches, newest first
+func (m *Coaches) GetByPlayerID(ctx context.Context, playerID int) ([]models.CoachLink, error) {
+	return m.list(func(link *models.CoachLink) bool { return link.PlayerID == playerID }), nil
+}
+
+// list returns the links matching keep, newest first
+func (m *Coaches) list(keep func(*models.CoachLink) bool) []models.CoachLink {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	var links []models.CoachLink
+	for _, link := range m.s.coachLinks {
+		if keep(link) {
+			links = append(links, *m.s.withNames(link))
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].ID > links[j].ID
+	})
+	return links
+}
+
+// Invite creates a pending link between a coach and a player, sent by one of
+// them, returning models.ErrLinkExists if they are already linked or invited
+func (m *Coaches) Invite(ctx context.Context, coachID, playerID, invitedBy int) (*models.CoachLink, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for _, link := range m.s.coachLinks {
+		if link.CoachID == coachID && link.PlayerID == playerID {
+			return nil, models.ErrLinkExists
+		}
+	}
+
+	link := &models.CoachLink{
+		ID:        m.s.nextID(),
+		CoachID:   coachID,
+		PlayerID:  playerID,
+		InvitedBy: invitedBy,
+		Status:    models.LinkPending,
+		CreatedAt: time.Now(),
+	}
+	m.s.coachLinks[link.ID] = link
+	return m.s.withNames(link), nil
+}
+
+// Accept marks a pending link as accepted
+func (m *Coaches) Accept(ctx context.Context, link *models.CoachLink) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.coachLinks[link.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	stored.Status = models.LinkAccepted
+	stored.AcceptedAt = &now
+	link.Status = stored.Status
+	link.AcceptedAt = copyTime(stored.AcceptedAt)
+	return nil
+}
+
+// Delete removes a link, declining an invitation or revoking access
+func (m *Coaches) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.coachLinks, id)
+	return nil
+}
+
+// CanView reports whether a user may view the data of another user. Admins
+// may view everyone, and coaches the players with an accepted link; access
+// ends if the coach stops being a coach.
+func (m *Coaches) CanView(ctx context.Context, viewerID, ownerID int) (bool, error) {
+	if viewerID == ownerID {
+		return true, nil
+	}
+
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	viewer, ok := m.s.users[viewerID]
+	if !ok {
+		return false, nil
+	}
+	if viewer.Role == models.RoleAdmin {
+		return true, nil
+	}
+	if viewer.Role != models.RoleCoach {
+		return false, nil
+	}
+
+	for _, link := range m.s.coachLinks {
+		if link.CoachID == viewerID && link.PlayerID == ownerID && link.Status == models.LinkAccepted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// withNames returns a copy of the link with the names and emails of both
+// sides filled in. The caller must hold the lock.
+func (s *Store) withNames(link *models.CoachLink) *models.CoachLink {
+	c := *link
+	c.AcceptedAt = copyTime(link.AcceptedAt)
+	if coach, ok := s.users[link.CoachID]; ok {
+		c.CoachName, c.CoachEmail = coach.Name, coach.Email
+	}
+	if player, ok := s.users[link.PlayerID]; ok {
+		c.PlayerName, c.PlayerEmail = player.Name, player.Email
+	}
+	return &c
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Errors stores error entries in memory
+type Errors struct {
+	s *Store
+}
+
+// GetByID retrieves an error entry by ID
+func (m *Errors) GetByID(ctx context.Context, id int) (*models.ErrorEntry, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	entry, ok := m.s.errors[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return copyErrorEntry(entry), nil
+}
+
+// GetBySessionID retrieves all error entries for a session in the order they happened
+func (m *Errors) GetBySessionID(ctx context.Context, sessionID int) ([]models.ErrorEntry, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	var entries []models.ErrorEntry
+	for _, entry := range m.s.entriesBySession()[sessionID] {
+		entries = append(entries, *copyErrorEntry(entry))
+	}
+	return entries, nil
+}
+
+// Create adds a new error entry to an existing session. A zero RecordedAt
+// defaults to the current time.
+func (m *Errors) Create(ctx context.Context, entry *models.ErrorEntry) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.sessions[entry.SessionID]; !ok {
+		return pgx.ErrNoRows
+	}
+
+	m.s.createErrorEntry(entry)
+	return nil
+}
+
+// createErrorEntry implements Create. The caller must hold the write lock.
+func (s *Store) createErrorEntry(entry *models.ErrorEntry) {
+	now := time.Now()
+	if entry.RecordedAt.IsZero() {
+		entry.RecordedAt = now
+	}
+	entry.ID = s.nextID()
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+
+	s.errors[entry.ID] = copyErrorEntry(entry)
+}
+
+// Update changes the count, categories and time of an error entry
+func (m *Errors) Update(ctx context.Context, entry *models.ErrorEntry) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.errors[entry.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	entry.UpdatedAt = time.Now()
+	stored.Count = entry.Count
+	stored.Stroke = entry.Stroke
+	stored.Side = entry.Side
+	stored.Outcome = entry.Outcome
+	stored.RecordedAt = entry.RecordedAt
+	stored.UpdatedAt = entry.UpdatedAt
+	return nil
+}
+
+// Delete removes an error entry
+func (m *Errors) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.errors, id)
+	return nil
+}
+
+// sortErrorEntries orders entries by when they happened, then by ID
+func sortErrorEntries(entries []*models.ErrorEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.RecordedAt.Equal(b.RecordedAt) {
+			return a.RecordedAt.Before(b.RecordedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// copyErrorEntry returns a copy of an error entry
+func copyErrorEntry(entry *models.ErrorEntry) *models.ErrorEntry {
+	c := *entry
+	c.ClientID = copyString(entry.ClientID)
+	return &c
+}
@@ -0,0 +1,123 @@
+// Package memory implements the model stores in memory, for demos, frontend
+// development and tests. Nothing is persisted, and features that only exist
+// in Postgres, such as points, comments and sync, are not covered.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// Store holds all data behind a single lock. Its stores share it, so for
+// example deleting a session also removes its error entries and share links.
+// Values are copied in and out, so callers never share memory with the store.
+type Store struct {
+	mu sync.RWMutex
+
+	users      map[int]*models.User
+	sessions   map[int]*models.Session
+	errors     map[int]*models.ErrorEntry
+	shares     map[int]*models.SharedLink
+	opponents  map[int]*models.Opponent
+	coachLinks map[int]*models.CoachLink
+
+	refreshTokens map[string]*refreshToken // By token hash
+	revokedJTIs   map[string]time.Time     // Expiry of each revoked access token
+	validAfter    map[int]time.Time        // Per user, when all tokens were last revoked
+
+	buckets       map[string]time.Time     // When each rate limit bucket is full again
+	loginFailures map[string]*loginFailure // By rate limit key
+
+	lastID int
+}
+
+// New returns an empty store
+func New() *Store {
+	return &Store{
+		users:         make(map[int]*models.User),
+		sessions:      make(map[int]*models.Session),
+		errors:        make(map[int]*models.ErrorEntry),
+		shares:        make(map[int]*models.SharedLink),
+		opponents:     make(map[int]*models.Opponent),
+		coachLinks:    make(map[int]*models.CoachLink),
+		refreshTokens: make(map[string]*refreshToken),
+		revokedJTIs:   make(map[string]time.Time),
+		validAfter:    make(map[int]time.Time),
+		buckets:       make(map[string]time.Time),
+		loginFailures: make(map[string]*loginFailure),
+	}
+}
+
+// Users returns the user store
+func (s *Store) Users() *Users { return &Users{s} }
+
+// Sessions returns the session store
+func (s *Store) Sessions() *Sessions { return &Sessions{s} }
+
+// Errors returns the error entry store
+func (s *Store) Errors() *Errors { return &Errors{s} }
+
+// Shares returns the share link store
+func (s *Store) Shares() *Shares { return &Shares{s} }
+
+// Tokens returns the token store
+func (s *Store) Tokens() *Tokens { return &Tokens{s} }
+
+// Opponents returns the opponent store
+func (s *Store) Opponents() *Opponents { return &Opponents{s} }
+
+// Coaches returns the coach link store
+func (s *Store) Coaches() *Coaches { return &Coaches{s} }
+
+// RateLimits returns the rate limit store
+func (s *Store) RateLimits() *RateLimits { return &RateLimits{s} }
+
+// The memory stores implement the model and rate limit stores
+var (
+	_ models.UserStore     = (*Users)(nil)
+	_ models.SessionStore  = (*Sessions)(nil)
+	_ models.ErrorStore    = (*Errors)(nil)
+	_ models.ShareStore    = (*Shares)(nil)
+	_ models.TokenStore    = (*Tokens)(nil)
+	_ models.OpponentStore = (*Opponents)(nil)
+	_ models.CoachStore    = (*Coaches)(nil)
+	_ ratelimit.Store      = (*RateLimits)(nil)
+)
+
+// nextID returns a new ID. IDs are unique across all entities, which keeps
+// the bookkeeping simple and is allowed by every store's contract. The caller
+// must hold the write lock.
+func (s *Store) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+// copyString returns a copy of a nullable string
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+// copyInt returns a copy of a nullable integer
+func copyInt(n *int) *int {
+	if n == nil {
+		return nil
+	}
+	v := *n
+	return &v
+}
+
+// copyTime returns a copy of a nullable time
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
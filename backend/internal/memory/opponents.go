@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Opponents stores opponents in memory
+type Opponents struct {
+	s *Store
+}
+
+// GetByID retrieves an opponent by ID with its session count
+func (m *Opponents) GetByID(ctx context.Context, id int) (*models.Opponent, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	opponent, ok := m.s.opponents[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return m.s.withSessionCount(opponent), nil
+}
+
+// GetByUserID retrieves all opponents of a user, alphabetically
+func (m *Opponents) GetByUserID(ctx context.Context, userID int) ([]models.Opponent, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	var opponents []models.Opponent
+	for _, opponent := range m.s.opponents {
+		if opponent.UserID == userID {
+			opponents = append(opponents, *m.s.withSessionCount(opponent))
+		}
+	}
+
+	sort.Slice(opponents, func(i, j int) bool {
+		return strings.ToLower(opponents[i].Name) < strings.ToLower(opponents[j].Name)
+	})
+	return opponents, nil
+}
+
+// FindOrCreate returns the opponent of a user matching name case-insensitively,
+// creating it if there is none
+func (m *Opponents) FindOrCreate(ctx context.Context, userID int, name string) (*models.Opponent, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	opponent := m.s.findOrCreateOpponent(userID, name)
+	c := *opponent
+	return &c, nil
+}
+
+// findOrCreateOpponent implements FindOrCreate. The caller must hold the
+// write lock.
+func (s *Store) findOrCreateOpponent(userID int, name string) *models.Opponent {
+	name = models.NormalizeOpponentName(name)
+	if opponent := s.opponentByName(userID, name); opponent != nil {
+		return opponent
+	}
+
+	now := time.Now()
+	opponent := &models.Opponent{
+		ID:        s.nextID(),
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.opponents[opponent.ID] = opponent
+	return opponent
+}
+
+// Rename changes the name of an opponent and of all sessions played against
+// it, returning models.ErrOpponentExists if the user has another opponent of
+// that name
+func (m *Opponents) Rename(ctx context.Context, opponent *models.Opponent, name string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.opponents[opponent.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	name = models.NormalizeOpponentName(name)
+	if other := m.s.opponentByName(stored.UserID, name); other != nil && other.ID != stored.ID {
+		return models.ErrOpponentExists
+	}
+
+	now := time.Now()
+	stored.Name = name
+	stored.UpdatedAt = now
+	opponent.Name = name
+	opponent.UpdatedAt = now
+
+	for _, session := range m.s.sessions {
+		if session.OpponentID != nil && *session.OpponentID == stored.ID {
+			session.OpponentName = name
+			session.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+// Merge moves all sessions of the source opponents to the target opponent and
+// deletes the sources. Sources that do not belong to the target's user are ignored.
+func (m *Opponents) Merge(ctx context.Context, target *models.Opponent, sourceIDs []int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	sources := make(map[int]bool)
+	for _, id := range sourceIDs {
+		if opponent, ok := m.s.opponents[id]; ok && opponent.UserID == target.UserID && id != target.ID {
+			sources[id] = true
+		}
+	}
+
+	now := time.Now()
+	for _, session := range m.s.sessions {
+		if session.OpponentID != nil && sources[*session.OpponentID] {
+			id := target.ID
+			session.OpponentID = &id
+			session.OpponentName = target.Name
+			session.UpdatedAt = now
+		}
+	}
+
+	for id := range sources {
+		delete(m.s.opponents, id)
+	}
+	return nil
+}
+
+// opponentByName finds the opponent of a user with the given name, ignoring
+// case. The caller must hold the lock.
+func (s *Store) opponentByName(userID int, name string) *models.Opponent {
+	for _, opponent := range s.opponents {
+		if opponent.UserID == userID && strings.EqualFold(opponent.Name, name) {
+			return opponent
+		}
+	}
+	return nil
+}
+
+// withSessionCount returns a copy of an opponent with the number of sessions
+// played against it. The caller must hold the lock.
+func (s *Store) withSessionCount(opponent *models.Opponent) *models.Opponent {
+	c := *opponent
+	c.SessionCount = 0
+	for _, session := range s.sessions {
+		if session.OpponentID != nil && *session.OpponentID == opponent.ID {
+			c.SessionCount++
+		}
+	}
+	return &c
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// loginFailure counts the consecutive failed logins of a key
+type loginFailure struct {
+	failures     int
+	lastFailedAt time.Time
+	lockedUntil  time.Time
+}
+
+// RateLimits stores rate limit buckets and failed login counts in memory
+type RateLimits struct {
+	s *Store
+}
+
+// Take takes a token from the bucket under key. It returns zero if one was
+// available, or else how long until one will be.
+func (m *RateLimits) Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	fullAt, ok := m.s.buckets[key]
+	if !ok || fullAt.Before(now) {
+		fullAt = now
+	}
+	if wait := limit.Wait(fullAt.Sub(now)); wait > 0 {
+		return wait, nil
+	}
+
+	m.s.buckets[key] = fullAt.Add(limit.Every)
+	return 0, nil
+}
+
+// LockedOut returns how much longer key is locked out, or zero if it is not
+func (m *RateLimits) LockedOut(ctx context.Context, key string) (time.Duration, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	failure, ok := m.s.loginFailures[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := time.Until(failure.lockedUntil); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// RecordFailure counts a failed attempt for key and returns how long key is
+// now locked out under the policy, or zero if it is not
+func (m *RateLimits) RecordFailure(ctx context.Context, key string, policy ratelimit.LockoutPolicy) (time.Duration, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	failure, ok := m.s.loginFailures[key]
+	if !ok || now.Sub(failure.lastFailedAt) > policy.Reset {
+		failure = &loginFailure{}
+		m.s.loginFailures[key] = failure
+	}
+	failure.failures++
+	failure.lastFailedAt = now
+
+	lockout := policy.Duration(failure.failures)
+	if lockout > 0 {
+		failure.lockedUntil = now.Add(lockout)
+	}
+	return lockout, nil
+}
+
+// ClearFailures forgets the failed attempts of key, e.g. after a successful login
+func (m *RateLimits) ClearFailures(ctx context.Context, key string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.loginFailures, key)
+	return nil
+}
+
+// Sweep deletes the buckets that have filled up again, which are the same as
+// none, and the failures forgotten after reset whose lockout has passed
+func (m *RateLimits) Sweep(ctx context.Context, reset time.Duration) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	for k, fullAt := range m.s.buckets {
+		if fullAt.Before(now) {
+			delete(m.s.buckets, k)
+		}
+	}
+	for k, failure := range m.s.loginFailures {
+		if now.Sub(failure.lastFailedAt) > reset && failure.lockedUntil.Before(now) {
+			delete(m.s.loginFailures, k)
+		}
+	}
+	return nil
+}
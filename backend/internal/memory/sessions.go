@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Sessions stores sessions in memory. Points cannot be logged against the
+// memory store, so every session counts as a practice.
+type Sessions struct {
+	s *Store
+}
+
+// GetByID retrieves a session by ID with its error totals
+func (m *Sessions) GetByID(ctx context.Context, id int) (*models.Session, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	session, ok := m.s.sessions[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return withTotals(session, m.s.entriesBySession()[id]), nil
+}
+
+// List retrieves a page of the sessions of a user matching the filter, in
+// the requested order, continuing after the cursor if one is given
+func (m *Sessions) List(ctx context.Context, userID int, opts models.SessionListOptions) ([]models.Session, *models.SessionCursor, error) {
+	if opts.Limit <= 0 || opts.Limit > models.MaxSessionPageSize {
+		opts.Limit = models.DefaultSessionPageSize
+	}
+	switch opts.Sort {
+	case models.SessionSortErrors, models.SessionSortName:
+	default:
+		opts.Sort = models.SessionSortDate
+	}
+
+	m.s.mu.RLock()
+	matching := m.s.matchingSessions(userID, opts.Filter)
+	m.s.mu.RUnlock()
+
+	order := func(a, b *models.Session) int {
+		c := compareSessions(opts.Sort, a, b)
+		if opts.Desc {
+			return -c
+		}
+		return c
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return order(matching[i], matching[j]) < 0
+	})
+
+	if after := opts.After; after != nil {
+		last := &models.Session{ID: after.ID, SessionDate: after.Date, ErrorCount: after.Errors, Name: after.Name}
+		i := sort.Search(len(matching), func(i int) bool {
+			return order(matching[i], last) > 0
+		})
+		matching = matching[i:]
+	}
+
+	var next *models.SessionCursor
+	if len(matching) > opts.Limit {
+		matching = matching[:opts.Limit]
+		last := matching[len(matching)-1]
+		next = &models.SessionCursor{Sort: opts.Sort, Desc: opts.Desc, ID: last.ID}
+		switch opts.Sort {
+		case models.SessionSortErrors:
+			next.Errors = last.ErrorCount
+		case models.SessionSortName:
+			next.Name = strings.ToLower(last.Name)
+		default:
+			next.Date = last.SessionDate
+		}
+	}
+
+	sessions := make([]models.Session, len(matching))
+	for i, session := range matching {
+		sessions[i] = *session
+	}
+	return sessions, next, nil
+}
+
+// compareSessions orders two sessions by a sort key, breaking ties by ID
+func compareSessions(key string, a, b *models.Session) int {
+	var c int
+	switch key {
+	case models.SessionSortErrors:
+		c = a.ErrorCount - b.ErrorCount
+	case models.SessionSortName:
+		c = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	default:
+		c = a.SessionDate.Compare(b.SessionDate)
+	}
+	if c != 0 {
+		return c
+	}
+	return a.ID - b.ID
+}
+
+// Create adds a new session
+func (m *Sessions) Create(ctx context.Context, session *models.Session) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.createSession(session)
+	return nil
+}
+
+// createSession implements Create. The caller must hold the write lock.
+func (s *Store) createSession(session *models.Session) {
+	now := time.Now()
+	session.ID = s.nextID()
+	session.CreatedAt = now
+	session.UpdatedAt = now
+
+	s.sessions[session.ID] = copySession(session)
+}
+
+// Update changes the name, opponent and date of a session
+func (m *Sessions) Update(ctx context.Context, session *models.Session) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.sessions[session.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	session.UpdatedAt = time.Now()
+	stored.Name = session.Name
+	stored.OpponentID = copyInt(session.OpponentID)
+	stored.OpponentName = session.OpponentName
+	stored.SessionDate = session.SessionDate
+	stored.UpdatedAt = session.UpdatedAt
+	return nil
+}
+
+// Delete removes a session together with its error entries and share links
+func (m *Sessions) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.sessions, id)
+	for entryID, entry := range m.s.errors {
+		if entry.SessionID == id {
+			delete(m.s.errors, entryID)
+		}
+	}
+	for linkID, link := range m.s.shares {
+		if link.SessionID == id {
+			delete(m.s.shares, linkID)
+		}
+	}
+	return nil
+}
+
+// GetTotalsByUserID retrieves the error total of every matching session of a
+// user, oldest first
+func (m *Sessions) GetTotalsByUserID(ctx context.Context, userID int, filter models.SessionFilter) ([]models.SessionTotal, error) {
+	m.s.mu.RLock()
+	matching := m.s.matchingSessions(userID, filter)
+	m.s.mu.RUnlock()
+
+	sort.Slice(matching, func(i, j int) bool {
+		return compareSessions(models.SessionSortDate, matching[i], matching[j]) < 0
+	})
+
+	var totals []models.SessionTotal
+	for _, session := range matching {
+		totals = append(totals, models.SessionTotal{
+			SessionID:    session.ID,
+			Name:         session.Name,
+			OpponentName: session.OpponentName,
+			SessionDate:  session.SessionDate,
+			ErrorCount:   session.ErrorCount,
+		})
+	}
+	return totals, nil
+}
+
+// Import adds the given sessions of a user and their error entries at once.
+// Opponents are matched by name and created on first use.
+func (m *Sessions) Import(ctx context.Context, userID int, sessions []models.SessionWithErrors) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for i := range sessions {
+		session := &sessions[i]
+		session.UserID = userID
+
+		if name := models.NormalizeOpponentName(session.OpponentName); name != "" {
+			opponent := m.s.findOrCreateOpponent(userID, name)
+			session.OpponentID = &opponent.ID
+			session.OpponentName = opponent.Name
+		}
+
+		m.s.createSession(&session.Session)
+
+		for j := range session.Errors {
+			entry := &session.Errors[j]
+			entry.SessionID = session.ID
+			m.s.createErrorEntry(entry)
+		}
+	}
+
+	return nil
+}
+
+// Export calls fn with every session of a user and its error entries, oldest
+// first. The sessions are copied up front, so fn runs without holding the lock.
+func (m *Sessions) Export(ctx context.Context, userID int, fn func(session *models.SessionWithErrors) error) error {
+	m.s.mu.RLock()
+	bySession := m.s.entriesBySession()
+	var sessions []models.SessionWithErrors
+	for _, session := range m.s.sessions {
+		if session.UserID != userID {
+			continue
+		}
+		entries := bySession[session.ID]
+		export := models.SessionWithErrors{
+			Session: *withTotals(session, entries),
+			Errors:  make([]models.ErrorEntry, len(entries)),
+		}
+		for i, entry := range entries {
+			export.Errors[i] = *copyErrorEntry(entry)
+		}
+		sessions = append(sessions, export)
+	}
+	m.s.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return compareSessions(models.SessionSortDate, &sessions[i].Session, &sessions[j].Session) < 0
+	})
+
+	for i := range sessions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchingSessions returns copies of the sessions of a user matching the
+// filter, with their error totals. The type filter needs points, which are
+// only kept in Postgres, so the API refuses it in memory and it is ignored
+// here. The caller must hold the lock.
+func (s *Store) matchingSessions(userID int, filter models.SessionFilter) []*models.Session {
+	bySession := s.entriesBySession()
+	opponent := strings.ToLower(models.NormalizeOpponentName(filter.Opponent))
+
+	var matching []*models.Session
+	for _, stored := range s.sessions {
+		if stored.UserID != userID {
+			continue
+		}
+		if !filter.From.IsZero() && stored.SessionDate.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !stored.SessionDate.Before(filter.To) {
+			continue
+		}
+		if opponent != "" && strings.ToLower(stored.OpponentName) != opponent {
+			continue
+		}
+		if filter.OpponentID != 0 && (stored.OpponentID == nil || *stored.OpponentID != filter.OpponentID) {
+			continue
+		}
+
+		session := withTotals(stored, bySession[stored.ID])
+		if filter.MinErrors != nil && session.ErrorCount < *filter.MinErrors {
+			continue
+		}
+		if filter.MaxErrors != nil && session.ErrorCount > *filter.MaxErrors {
+			continue
+		}
+		matching = append(matching, session)
+	}
+	return matching
+}
+
+// entriesBySession groups the error entries by session, each group in the
+// order the errors happened. The caller must hold the lock.
+func (s *Store) entriesBySession() map[int][]*models.ErrorEntry {
+	bySession := make(map[int][]*models.ErrorEntry)
+	for _, entry := range s.errors {
+		bySession[entry.SessionID] = append(bySession[entry.SessionID], entry)
+	}
+	for _, entries := range bySession {
+		sortErrorEntries(entries)
+	}
+	return bySession
+}
+
+// withTotals returns a copy of a session with the totals of its error entries
+func withTotals(session *models.Session, entries []*models.ErrorEntry) *models.Session {
+	c := copySession(session)
+	for _, entry := range entries {
+		c.ErrorCount += entry.Count
+		c.AddCategoryTotals(entry.Stroke, entry.Side, entry.Outcome, entry.Count)
+	}
+	return c
+}
+
+// copySession returns a copy of a session without its computed totals
+func copySession(session *models.Session) *models.Session {
+	return &models.Session{
+		ID:           session.ID,
+		UserID:       session.UserID,
+		ClientID:     copyString(session.ClientID),
+		Name:         session.Name,
+		OpponentID:   copyInt(session.OpponentID),
+		OpponentName: session.OpponentName,
+		SessionDate:  session.SessionDate,
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+	}
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// shareTokenBytes is the amount of randomness in a share token
+const shareTokenBytes = 32
+
+// Shares stores share links in memory
+type Shares struct {
+	s *Store
+}
+
+// Create generates a new token for the link and stores it. A zero or negative
+// expiry defaults to models.DefaultShareExpiry.
+func (m *Shares) Create(ctx context.Context, link *models.SharedLink, expiry time.Duration) error {
+	if expiry <= 0 {
+		expiry = models.DefaultShareExpiry
+	}
+
+	token, err := models.RandomToken(shareTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.sessions[link.SessionID]; !ok {
+		return pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	link.ID = m.s.nextID()
+	link.Token = token
+	link.ExpiresAt = now.Add(expiry)
+	link.CreatedAt = now
+
+	stored := *link
+	m.s.shares[link.ID] = &stored
+	return nil
+}
+
+// GetByToken retrieves a shared link by its token, whether or not it has expired
+func (m *Shares) GetByToken(ctx context.Context, token string) (*models.SharedLink, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	for _, link := range m.s.shares {
+		if link.Token == token {
+			c := *link
+			return &c, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// GetActiveBySessionID retrieves all unexpired links for a session, newest first
+func (m *Shares) GetActiveBySessionID(ctx context.Context, sessionID int) ([]models.SharedLink, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	var links []models.SharedLink
+	for _, link := range m.s.shares {
+		if link.SessionID == sessionID && !link.Expired() {
+			links = append(links, *link)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].CreatedAt.After(links[j].CreatedAt)
+		}
+		return links[i].ID > links[j].ID
+	})
+	return links, nil
+}
+
+// Revoke removes a single link from a session and reports whether it existed
+func (m *Shares) Revoke(ctx context.Context, sessionID int, token string) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for id, link := range m.s.shares {
+		if link.SessionID == sessionID && link.Token == token {
+			delete(m.s.shares, id)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RevokeAll removes every link for a session and returns how many were removed
+func (m *Shares) RevokeAll(ctx context.Context, sessionID int) (int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	var n int64
+	for id, link := range m.s.shares {
+		if link.SessionID == sessionID {
+			delete(m.s.shares, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// refreshTokenBytes is the amount of randomness in a refresh token
+const refreshTokenBytes = 32
+
+// refreshToken is a stored refresh token, kept under the hash of its plaintext
+type refreshToken struct {
+	userID    int
+	familyID  string
+	expiresAt time.Time
+	revoked   bool
+}
+
+// Tokens stores refresh tokens and revoked access tokens in memory
+type Tokens struct {
+	s *Store
+}
+
+// CreateRefreshToken starts a new token family for a user, e.g. on login, and
+// returns the plaintext refresh token
+func (m *Tokens) CreateRefreshToken(ctx context.Context, userID int) (string, error) {
+	familyID, err := models.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.insertRefreshToken(userID, familyID)
+}
+
+// insertRefreshToken stores a new refresh token in a family and returns its
+// plaintext. The caller must hold the write lock.
+func (s *Store) insertRefreshToken(userID int, familyID string) (string, error) {
+	token, err := models.RandomToken(refreshTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	s.refreshTokens[models.HashToken(token)] = &refreshToken{
+		userID:    userID,
+		familyID:  familyID,
+		expiresAt: time.Now().Add(models.RefreshTokenTTL),
+	}
+	return token, nil
+}
+
+// RotateRefreshToken exchanges a valid refresh token for a new one in the same
+// family and returns the new plaintext token and the owning user ID. Presenting
+// a token that was already rotated or revoked revokes the whole family and
+// returns models.ErrRefreshTokenReused.
+func (m *Tokens) RotateRefreshToken(ctx context.Context, token string) (string, int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	current, ok := m.s.refreshTokens[models.HashToken(token)]
+	if !ok {
+		return "", 0, models.ErrRefreshTokenInvalid
+	}
+
+	if current.revoked {
+		m.s.revokeRefreshTokens(func(t *refreshToken) bool { return t.familyID == current.familyID })
+		return "", 0, models.ErrRefreshTokenReused
+	}
+
+	if !time.Now().Before(current.expiresAt) {
+		return "", 0, models.ErrRefreshTokenInvalid
+	}
+
+	next, err := m.s.insertRefreshToken(current.userID, current.familyID)
+	if err != nil {
+		return "", 0, err
+	}
+	current.revoked = true
+
+	return next, current.userID, nil
+}
+
+// RevokeFamily revokes every token in the family of the given refresh token,
+// provided it belongs to the user. It reports whether the token was found.
+func (m *Tokens) RevokeFamily(ctx context.Context, userID int, token string) (bool, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	current, ok := m.s.refreshTokens[models.HashToken(token)]
+	if !ok || current.userID != userID {
+		return false, nil
+	}
+
+	m.s.revokeRefreshTokens(func(t *refreshToken) bool { return t.familyID == current.familyID })
+	return true, nil
+}
+
+// RevokeAllForUser revokes every refresh token of a user and rejects every
+// access token issued so far, logging them out everywhere
+func (m *Tokens) RevokeAllForUser(ctx context.Context, userID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	m.s.revokeRefreshTokens(func(t *refreshToken) bool { return t.userID == userID })
+	m.s.validAfter[userID] = time.Now()
+	return nil
+}
+
+// revokeRefreshTokens revokes the refresh tokens matching a condition. The
+// caller must hold the write lock.
+func (s *Store) revokeRefreshTokens(match func(t *refreshToken) bool) {
+	for _, t := range s.refreshTokens {
+		if match(t) {
+			t.revoked = true
+		}
+	}
+}
+
+// RevokeAccessToken denylists an access token by its JWT ID until it expires
+func (m *Tokens) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	// Entries past their expiry are useless, so clean them up as we go
+	now := time.Now()
+	for id, expiry := range m.s.revokedJTIs {
+		if expiry.Before(now) {
+			delete(m.s.revokedJTIs, id)
+		}
+	}
+
+	if _, ok := m.s.revokedJTIs[jti]; !ok {
+		m.s.revokedJTIs[jti] = expiresAt
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token has been denylisted,
+// or was issued to the user before all their tokens were revoked. Issue times
+// have microsecond precision, so the cut-off is truncated to match.
+func (m *Tokens) IsAccessTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	if _, ok := m.s.revokedJTIs[jti]; ok {
+		return true, nil
+	}
+	validAfter, ok := m.s.validAfter[userID]
+	return ok && issuedAt.Before(validAfter.Truncate(time.Microsecond)), nil
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Users stores user accounts in memory
+type Users struct {
+	s *Store
+}
+
+// GetByID retrieves a user by ID
+func (u *Users) GetByID(ctx context.Context, id int) (*models.User, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	user, ok := u.s.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return copyUser(user), nil
+}
+
+// GetByEmail retrieves a user by email
+func (u *Users) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	user := u.s.userByEmail(email)
+	if user == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return copyUser(user), nil
+}
+
+// Create adds a new user, returning models.ErrEmailTaken if the email is in use
+func (u *Users) Create(ctx context.Context, user *models.User) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	if u.s.userByEmail(user.Email) != nil {
+		return models.ErrEmailTaken
+	}
+
+	if user.Role == "" {
+		user.Role = models.RolePlayer
+	}
+	now := time.Now()
+	user.ID = u.s.nextID()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	u.s.users[user.ID] = copyUser(user)
+	return nil
+}
+
+// Update changes the name, email and role of a user
+func (u *Users) Update(ctx context.Context, user *models.User) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	stored, ok := u.s.users[user.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	if other := u.s.userByEmail(user.Email); other != nil && other.ID != user.ID {
+		return models.ErrEmailTaken
+	}
+
+	user.UpdatedAt = time.Now()
+	stored.Name = user.Name
+	stored.Email = user.Email
+	stored.Role = user.Role
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+// UpdatePassword updates a user's password
+func (u *Users) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	if stored, ok := u.s.users[userID]; ok {
+		stored.PasswordHash = passwordHash
+		stored.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// UpdateLastLogin records the current time as the user's last login
+func (u *Users) UpdateLastLogin(ctx context.Context, user *models.User) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	stored, ok := u.s.users[user.ID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	now := time.Now()
+	stored.LastLoginAt = &now
+	user.LastLoginAt = copyTime(&now)
+	return nil
+}
+
+// userByEmail finds a user by email. The caller must hold the lock.
+func (s *Store) userByEmail(email string) *models.User {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+// copyUser returns a copy of a user
+func copyUser(user *models.User) *models.User {
+	c := *user
+	c.LastLoginAt = copyTime(user.LastLoginAt)
+	return &c
+}
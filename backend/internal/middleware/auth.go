@@ -2,109 +2,148 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
 )
 
 // User ID key for storing in context
 type contextKey string
 const UserIDKey contextKey = "userID"
 
-// Authenticate middleware verifies JWT tokens and sets user information in the context
-func Authenticate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get token from the Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header is required", http.StatusUnauthorized)
-			return
-		}
+// ClaimsKey is the context key for the claims of the access token
+const ClaimsKey contextKey = "claims"
 
-		// Check if the header has the correct format
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Authorization header format must be 'Bearer {token}'", http.StatusUnauthorized)
-			return
-		}
+// AccessTokenTTL is how long an access token is valid; clients renew it with a refresh token
+const AccessTokenTTL = 15 * time.Minute
 
-		// Extract the token
-		tokenStr := parts[1]
+func init() {
+	// Issue times are compared with the time all of a user's tokens were
+	// revoked, so a token issued in the same second as a password reset must
+	// still be told apart from one issued right after it
+	jwt.TimePrecision = time.Microsecond
+}
 
-		// Parse and validate the token
-		userID, err := validateToken(tokenStr)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
-			return
-		}
+// TokenRevocations reports whether an access token has been revoked, e.g. by
+// logging out or resetting the password
+type TokenRevocations interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error)
+}
 
-		// Set user ID in context
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// Authenticate returns middleware that verifies JWT tokens signed with secret,
+// rejects revoked ones and sets user information in the context. Why a token
+// was rejected is not revealed beyond it being invalid.
+func Authenticate(secret []byte, revocations TokenRevocations) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get token from the Authorization header
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				apperr.Write(w, r, apperr.Unauthorized("Authorization header is required"))
+				return
+			}
+
+			// Check if the header has the correct format
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				apperr.Write(w, r, apperr.Unauthorized("Authorization header format must be 'Bearer {token}'"))
+				return
+			}
+
+			// Extract the token
+			tokenStr := parts[1]
+
+			// Parse and validate the token
+			claims, err := validateToken(secret, tokenStr)
+			if err != nil {
+				apperr.Write(w, r, apperr.Wrap(err, apperr.CodeUnauthorized, "Invalid or expired token"))
+				return
+			}
+
+			// Reject tokens revoked by logout or password reset
+			if revocations != nil {
+				revoked, err := revocations.IsAccessTokenRevoked(r.Context(), claims.ID, claims.UserID, claims.IssuedAt.Time)
+				if err != nil {
+					apperr.Write(w, r, apperr.Internal(err, "Failed to verify token"))
+					return
+				}
+				if revoked {
+					apperr.Write(w, r, apperr.Unauthorized("Invalid or expired token"))
+					return
+				}
+			}
+
+			// Set user ID and claims in context
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 // Claims represents the JWT claims
 type Claims struct {
 	UserID int `json:"user_id"`
-	jwt.StandardClaims
+	jwt.RegisteredClaims
 }
 
 // ValidateToken parses and validates a JWT token
-func validateToken(tokenStr string) (int, error) {
-	// Get secret key from environment variable
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "your-default-secret-key-for-development" // Default for development
-	}
-
+func validateToken(secret []byte, tokenStr string) (*Claims, error) {
 	// Parse the JWT token
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate the algorithm
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(secretKey), nil
+		return secret, nil
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// Check if the token is valid
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
 		// Check if token is expired
-		if claims.ExpiresAt < time.Now().Unix() {
-			return 0, errors.New("token expired")
+		if claims.ExpiresAt == nil || claims.ExpiresAt.Before(time.Now()) {
+			return nil, errors.New("token expired")
 		}
-		return claims.UserID, nil
+		// Tokens without an ID or issue time cannot be revoked
+		if claims.ID == "" || claims.IssuedAt == nil {
+			return nil, errors.New("token has no ID or issue time")
+		}
+		return claims, nil
 	}
 
-	return 0, errors.New("invalid token")
+	return nil, errors.New("invalid token")
 }
 
-// GenerateToken creates a new JWT token for a user
-func GenerateToken(userID int) (string, error) {
-	// Get secret key from environment variable
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "your-default-secret-key-for-development" // Default for development
-	}
+// GenerateToken creates a new JWT token for a user, signed with secret
+func GenerateToken(secret []byte, userID int) (string, error) {
+	// Set token expiration time
+	now := time.Now()
+	expirationTime := now.Add(AccessTokenTTL)
 
-	// Set token expiration time (24 hours)
-	expirationTime := time.Now().Add(24 * time.Hour)
+	// Generate a unique token ID so the token can be denylisted
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 
-	// Create claims with user ID and expiration time
+	// Create claims with user ID, token ID and expiration time
 	claims := &Claims{
 		UserID: userID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-			IssuedAt:  time.Now().Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "tennis-tracker",
 		},
 	}
@@ -113,7 +152,7 @@ func GenerateToken(userID int) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// Sign the token with the secret key
-	tokenString, err := token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString(secret)
 	if err != nil {
 		return "", err
 	}
@@ -129,3 +168,12 @@ func GetUserID(r *http.Request) (int, error) {
 	}
 	return userID, nil
 }
+
+// GetClaims extracts the access token claims from the request context
+func GetClaims(r *http.Request) (*Claims, error) {
+	claims, ok := r.Context().Value(ClaimsKey).(*Claims)
+	if !ok {
+		return nil, errors.New("claims not found in context")
+	}
+	return claims, nil
+}
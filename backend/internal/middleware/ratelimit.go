@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// maxPeekSize is the largest body RateLimit reads to find the email in;
+// larger bodies are only limited by IP
+const maxPeekSize = 64 << 10
+
+// RateLimiter takes tokens from rate limit buckets
+type RateLimiter interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error)
+}
+
+// bucket names a rate limit bucket and its limit
+type bucket struct {
+	key   string
+	limit ratelimit.Limit
+}
+
+// RateLimit returns middleware that throttles requests with token buckets:
+// one per client IP and, when the JSON body has an email, one per account
+// email. Scope keeps the buckets of different endpoints apart. Requests over
+// either limit get 429 with a Retry-After header. The client IP is the remote
+// address, so RealIP must run first behind a proxy. If the limiter fails, the
+// request is let through rather than locking everyone out.
+func RateLimit(limiter RateLimiter, scope string, byIP, byEmail ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buckets := []bucket{{scope + ":ip:" + clientIP(r), byIP}}
+			if email := peekEmail(r); email != "" {
+				buckets = append(buckets, bucket{scope + ":email:" + email, byEmail})
+			}
+
+			for _, b := range buckets {
+				wait, err := limiter.Take(r.Context(), b.key, b.limit)
+				if err != nil {
+					log.Printf("[%s] Rate limiting failed: %v", middleware.GetReqID(r.Context()), err)
+					continue
+				}
+				if wait > 0 {
+					RespondRateLimited(w, r, wait, "Too many requests")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RespondRateLimited sends a rate_limited error telling the client to retry
+// after wait, rounded up to whole seconds
+func RespondRateLimited(w http.ResponseWriter, r *http.Request, wait time.Duration, message string) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	err := apperr.New(apperr.CodeRateLimited, message)
+	err.Extensions = map[string]interface{}{"retry_after": retryAfter}
+	apperr.Write(w, r, err)
+}
+
+// clientIP returns the IP address of the client without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP sets the address without a port
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// peekEmail returns the normalized email field of a JSON request body, or ""
+// if it has none. The body is left for the handler to read.
+func peekEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, maxPeekSize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), r.Body), r.Body}
+	if err != nil || len(peeked) > maxPeekSize {
+		return ""
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(peeked, &body); err != nil {
+		return ""
+	}
+	// Normalized like the emails of accounts
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
@@ -1,7 +1,12 @@
 package middleware
 
 import (
+	"fmt"
+	"log"
 	"net/http"
+	"runtime/debug"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
 )
 
 // JSONContentType sets the content type to application/json for all responses
@@ -12,19 +17,23 @@ func JSONContentType(next http.Handler) http.Handler {
 	})
 }
 
-// CORS handles Cross-Origin Resource Sharing (CORS) for the API
-// Note: This is a simple implementation, consider using a library like github.com/go-chi/cors for production
-func CORS(next http.Handler) http.Handler {
+// Recoverer turns panics in later handlers into internal error responses and
+// logs them with their stack trace
+func Recoverer(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Aborting the response is how handlers signal a broken connection
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
 
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+			log.Printf("panic: %v\n%s", rec, debug.Stack())
+			apperr.Write(w, r, apperr.Internal(fmt.Errorf("panic: %v", rec), "Internal server error"))
+		}()
 
 		next.ServeHTTP(w, r)
 	})
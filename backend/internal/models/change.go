@@ -0,0 +1,369 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// Entities recorded in the change log
+const (
+	ChangeEntitySession = "session"
+	ChangeEntityError   = "error"
+	ChangeEntityShare   = "share"
+)
+
+// Change feed page sizes
+const (
+	DefaultChangesLimit = 500
+	MaxChangesLimit     = 1000
+)
+
+// Change is the latest change of a row. Deleted rows are tombstones and carry
+// no data; otherwise the current row is included.
+type Change struct {
+	Seq       int64       `json:"seq"`
+	Entity    string      `json:"entity"`
+	ID        int         `json:"id"`
+	ClientID  *string     `json:"client_id,omitempty"`
+	Deleted   bool        `json:"deleted"`
+	ChangedAt time.Time   `json:"changed_at"`
+	Session   *Session    `json:"session,omitempty"`
+	Entry     *ErrorEntry `json:"entry,omitempty"`
+	Share     *SharedLink `json:"share,omitempty"`
+}
+
+// FormatChangeCursor encodes a change sequence number as an opaque cursor
+func FormatChangeCursor(seq int64) string {
+	return strconv.FormatInt(seq, 10)
+}
+
+// ParseChangeCursor decodes a cursor; an empty cursor means the beginning
+func ParseChangeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return seq, nil
+}
+
+// ChangeService reads the change log
+type ChangeService struct {
+	DB *database.DB
+}
+
+// lockChanges locks the user row, serializing the writers of a user so their
+// changes commit in sequence order. Taking it before any other row lock keeps
+// the lock order the same everywhere.
+func lockChanges(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID)
+	return err
+}
+
+// withChanges runs fn in a transaction that records changes of a user
+func withChanges(ctx context.Context, db *database.DB, userID int, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockChanges(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// recordChange bumps the change sequence of a user and records that a row was
+// created, updated or deleted, replacing the previous change of the row
+func recordChange(ctx context.Context, tx pgx.Tx, userID int, entity string, entityID int, clientID *string, deleted bool) error {
+	_, err := tx.Exec(ctx, `
+		WITH next AS (
+			UPDATE users SET change_seq = change_seq + 1 WHERE id = $1 RETURNING change_seq
+		)
+		INSERT INTO changes (user_id, entity, entity_id, client_id, seq, deleted, changed_at)
+		SELECT $1, $2, $3, $4, change_seq, $5, NOW() FROM next
+		ON CONFLICT (user_id, entity, entity_id) DO UPDATE
+		SET seq = EXCLUDED.seq,
+		    deleted = EXCLUDED.deleted,
+		    client_id = COALESCE(EXCLUDED.client_id, changes.client_id),
+		    changed_at = EXCLUDED.changed_at
+	`, userID, entity, entityID, clientID, deleted)
+
+	return err
+}
+
+// changedRow identifies a row returned by a bulk UPDATE or DELETE
+type changedRow struct {
+	ID       int
+	ClientID *string
+}
+
+// changeRows runs a statement returning the id and client_id of the affected
+// rows, records a change for each of them and returns how many there were
+func changeRows(ctx context.Context, tx pgx.Tx, userID int, entity string, deleted bool, sql string, args ...interface{}) (int, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var changed []changedRow
+	for rows.Next() {
+		var row changedRow
+		if err := rows.Scan(&row.ID, &row.ClientID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changed = append(changed, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, row := range changed {
+		if err := recordChange(ctx, tx, userID, entity, row.ID, row.ClientID, deleted); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(changed), nil
+}
+
+// deleteSession deletes a session and records tombstones for it and for the
+// error entries and share links deleted along with it
+func deleteSession(ctx context.Context, tx pgx.Tx, userID, sessionID int) error {
+	_, err := changeRows(ctx, tx, userID, ChangeEntityError, true,
+		`DELETE FROM errors WHERE session_id = $1 RETURNING id, client_id`, sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = changeRows(ctx, tx, userID, ChangeEntityShare, true,
+		`DELETE FROM shared_links WHERE session_id = $1 RETURNING id, NULL::uuid`, sessionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = changeRows(ctx, tx, userID, ChangeEntitySession, true,
+		`DELETE FROM sessions WHERE id = $1 RETURNING id, client_id`, sessionID)
+	return err
+}
+
+// sessionOwner returns the ID of the user owning a session
+func sessionOwner(ctx context.Context, q querier, sessionID int) (int, error) {
+	var userID int
+	err := q.QueryRow(ctx, `SELECT user_id FROM sessions WHERE id = $1`, sessionID).Scan(&userID)
+	return userID, err
+}
+
+// Since returns up to limit changes of a user after the given sequence
+// number, oldest first, the cursor to pass next time and whether more
+// changes are waiting. A since of zero returns every live row and tombstone.
+func (s *ChangeService) Since(ctx context.Context, userID int, since int64, limit int) ([]Change, int64, bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	// Read the log and the rows from the same snapshot
+	tx, err := s.DB.Pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT seq, entity, entity_id, client_id, deleted, changed_at
+		FROM changes
+		WHERE user_id = $1 AND seq > $2
+		ORDER BY seq
+		LIMIT $3
+	`, userID, since, limit+1)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	var changes []Change
+	for rows.Next() {
+		var change Change
+		err := rows.Scan(
+			&change.Seq,
+			&change.Entity,
+			&change.ID,
+			&change.ClientID,
+			&change.Deleted,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, 0, false, err
+		}
+		changes = append(changes, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, false, err
+	}
+
+	more := len(changes) > limit
+	if more {
+		changes = changes[:limit]
+	}
+
+	cursor := since
+	if len(changes) > 0 {
+		cursor = changes[len(changes)-1].Seq
+	}
+
+	if err := loadChangedRows(ctx, tx, changes); err != nil {
+		return nil, 0, false, err
+	}
+
+	return changes, cursor, more, nil
+}
+
+// loadChangedRows fills in the current data of every change that is not a tombstone
+func loadChangedRows(ctx context.Context, tx pgx.Tx, changes []Change) error {
+	ids := make(map[string][]int)
+	for _, change := range changes {
+		if !change.Deleted {
+			ids[change.Entity] = append(ids[change.Entity], change.ID)
+		}
+	}
+
+	sessions := make(map[int]*Session)
+	if len(ids[ChangeEntitySession]) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT id, user_id, client_id, name, opponent_id, opponent_name, session_date, created_at, updated_at
+			FROM sessions
+			WHERE id = ANY($1)
+		`, ids[ChangeEntitySession])
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var session Session
+			err := rows.Scan(
+				&session.ID,
+				&session.UserID,
+				&session.ClientID,
+				&session.Name,
+				&session.OpponentID,
+				&session.OpponentName,
+				&session.SessionDate,
+				&session.CreatedAt,
+				&session.UpdatedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			sessions[session.ID] = &session
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	entries := make(map[int]*ErrorEntry)
+	if len(ids[ChangeEntityError]) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT id, session_id, client_id, count, COALESCE(stroke, ''), COALESCE(side, ''), COALESCE(outcome, ''),
+			       recorded_at, created_at, updated_at
+			FROM errors
+			WHERE id = ANY($1)
+		`, ids[ChangeEntityError])
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var entry ErrorEntry
+			err := rows.Scan(
+				&entry.ID,
+				&entry.SessionID,
+				&entry.ClientID,
+				&entry.Count,
+				&entry.Stroke,
+				&entry.Side,
+				&entry.Outcome,
+				&entry.RecordedAt,
+				&entry.CreatedAt,
+				&entry.UpdatedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			entries[entry.ID] = &entry
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	links := make(map[int]*SharedLink)
+	if len(ids[ChangeEntityShare]) > 0 {
+		rows, err := tx.Query(ctx, `
+			SELECT id, user_id, session_id, token, expires_at, created_at
+			FROM shared_links
+			WHERE id = ANY($1)
+		`, ids[ChangeEntityShare])
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var link SharedLink
+			err := rows.Scan(
+				&link.ID,
+				&link.UserID,
+				&link.SessionID,
+				&link.Token,
+				&link.ExpiresAt,
+				&link.CreatedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			links[link.ID] = &link
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		if change.Deleted {
+			continue
+		}
+		switch change.Entity {
+		case ChangeEntitySession:
+			change.Session = sessions[change.ID]
+		case ChangeEntityError:
+			change.Entry = entries[change.ID]
+		case ChangeEntityShare:
+			change.Share = links[change.ID]
+		}
+	}
+
+	return nil
+}
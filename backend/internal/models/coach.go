@@ -0,0 +1,213 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// Coach link statuses
+const (
+	LinkPending  = "pending"
+	LinkAccepted = "accepted"
+)
+
+// ErrLinkExists is returned when a coach and player are already linked or invited
+var ErrLinkExists = errors.New("coach link already exists")
+
+// CoachLink connects a coach to a player. Once accepted by whoever did not
+// send the invitation, the coach can view the player's sessions and stats.
+type CoachLink struct {
+	ID          int        `json:"id"`
+	CoachID     int        `json:"coach_id"`
+	CoachName   string     `json:"coach_name"`
+	CoachEmail  string     `json:"coach_email"`
+	PlayerID    int        `json:"player_id"`
+	PlayerName  string     `json:"player_name"`
+	PlayerEmail string     `json:"player_email"`
+	InvitedBy   int        `json:"invited_by"`
+	Status      string     `json:"status"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Involves reports whether the user is the coach or the player of the link
+func (l *CoachLink) Involves(userID int) bool {
+	return l.CoachID == userID || l.PlayerID == userID
+}
+
+// CoachService handles database operations for coach links
+type CoachService struct {
+	DB *database.DB
+}
+
+// coachLinkColumns selects a link together with the names of both sides
+const coachLinkColumns = `
+	SELECT l.id, l.coach_id, COALESCE(c.name, ''), c.email, l.player_id, COALESCE(p.name, ''), p.email,
+	       l.invited_by, l.status, l.accepted_at, l.created_at
+	FROM coach_links l
+	JOIN users c ON c.id = l.coach_id
+	JOIN users p ON p.id = l.player_id
+`
+
+// scanCoachLink scans a row selected with coachLinkColumns
+func scanCoachLink(row interface{ Scan(...interface{}) error }, link *CoachLink) error {
+	return row.Scan(
+		&link.ID,
+		&link.CoachID,
+		&link.CoachName,
+		&link.CoachEmail,
+		&link.PlayerID,
+		&link.PlayerName,
+		&link.PlayerEmail,
+		&link.InvitedBy,
+		&link.Status,
+		&link.AcceptedAt,
+		&link.CreatedAt,
+	)
+}
+
+// GetByID retrieves a coach link by ID
+func (s *CoachService) GetByID(ctx context.Context, id int) (*CoachLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var link CoachLink
+
+	row := s.DB.Pool.QueryRow(ctx, coachLinkColumns+`WHERE l.id = $1`, id)
+	if err := scanCoachLink(row, &link); err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetByCoachID retrieves the links of a coach to their players, newest first
+func (s *CoachService) GetByCoachID(ctx context.Context, coachID int) ([]CoachLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	return s.list(ctx, coachLinkColumns+`WHERE l.coach_id = $1 ORDER BY l.created_at DESC`, coachID)
+}
+
+// GetByPlayerID retrieves the links of a player to their coaches, newest first
+func (s *CoachService) GetByPlayerID(ctx context.Context, playerID int) ([]CoachLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	return s.list(ctx, coachLinkColumns+`WHERE l.player_id = $1 ORDER BY l.created_at DESC`, playerID)
+}
+
+// list runs a query selecting coach links
+func (s *CoachService) list(ctx context.Context, query string, args ...interface{}) ([]CoachLink, error) {
+	var links []CoachLink
+
+	rows, err := s.DB.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link CoachLink
+		if err := scanCoachLink(rows, &link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Invite creates a pending link between a coach and a player, sent by one of them
+func (s *CoachService) Invite(ctx context.Context, coachID, playerID, invitedBy int) (*CoachLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var id int
+
+	query := `
+		INSERT INTO coach_links (coach_id, player_id, invited_by)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	err := s.DB.Pool.QueryRow(ctx, query, coachID, playerID, invitedBy).Scan(&id)
+	if isUniqueViolation(err, "coach_links_coach_id_player_id_key") {
+		return nil, ErrLinkExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// Accept marks a pending link as accepted
+func (s *CoachService) Accept(ctx context.Context, link *CoachLink) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE coach_links
+		SET status = $2, accepted_at = NOW()
+		WHERE id = $1
+		RETURNING status, accepted_at
+	`
+
+	return s.DB.Pool.QueryRow(ctx, query, link.ID, LinkAccepted).Scan(&link.Status, &link.AcceptedAt)
+}
+
+// Delete removes a link, declining an invitation or revoking access
+func (s *CoachService) Delete(ctx context.Context, id int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM coach_links WHERE id = $1`
+
+	_, err := s.DB.Pool.Exec(ctx, query, id)
+
+	return err
+}
+
+// CanView reports whether a user may view the data of another user. Admins
+// may view everyone, and coaches the players with an accepted link; access
+// ends if the coach stops being a coach.
+func (s *CoachService) CanView(ctx context.Context, viewerID, ownerID int) (bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	if viewerID == ownerID {
+		return true, nil
+	}
+
+	var allowed bool
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND role = $3)
+		    OR EXISTS (
+		        SELECT 1
+		        FROM coach_links l
+		        JOIN users c ON c.id = l.coach_id
+		        WHERE l.coach_id = $1 AND l.player_id = $2 AND l.status = $4 AND c.role = $5
+		    )
+	`
+
+	err := s.DB.Pool.QueryRow(
+		ctx,
+		query,
+		viewerID,
+		ownerID,
+		RoleAdmin,
+		LinkAccepted,
+		RoleCoach,
+	).Scan(&allowed)
+
+	return allowed, err
+}
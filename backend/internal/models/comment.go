@@ -0,0 +1,156 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// MaxCommentLength is the longest comment body accepted, in characters
+const MaxCommentLength = 2000
+
+// Comment is feedback left on a session, or on one of its error entries when
+// ErrorID is set. Replies name their parent and share its target.
+type Comment struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"session_id"`
+	ErrorID    *int      `json:"error_id,omitempty"`
+	ParentID   *int      `json:"parent_id,omitempty"`
+	AuthorID   int       `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CommentService handles database operations for comments
+type CommentService struct {
+	DB *database.DB
+}
+
+// commentColumns selects a comment together with the name of its author
+const commentColumns = `
+	SELECT c.id, c.session_id, c.error_id, c.parent_id, c.author_id, COALESCE(u.name, ''), c.body,
+	       c.created_at, c.updated_at
+	FROM comments c
+	JOIN users u ON u.id = c.author_id
+`
+
+// scanComment scans a row selected with commentColumns
+func scanComment(row interface{ Scan(...interface{}) error }, comment *Comment) error {
+	return row.Scan(
+		&comment.ID,
+		&comment.SessionID,
+		&comment.ErrorID,
+		&comment.ParentID,
+		&comment.AuthorID,
+		&comment.AuthorName,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+}
+
+// GetByID retrieves a comment by ID
+func (s *CommentService) GetByID(ctx context.Context, id int) (*Comment, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var comment Comment
+
+	row := s.DB.Pool.QueryRow(ctx, commentColumns+`WHERE c.id = $1`, id)
+	if err := scanComment(row, &comment); err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetBySessionID retrieves the comments of a session, oldest first. If
+// errorID is not nil only the comments on that error entry are returned.
+func (s *CommentService) GetBySessionID(ctx context.Context, sessionID int, errorID *int) ([]Comment, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var comments []Comment
+
+	query := commentColumns + `
+		WHERE c.session_id = $1 AND ($2::integer IS NULL OR c.error_id = $2)
+		ORDER BY c.created_at, c.id
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, sessionID, errorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// Create inserts a new comment
+func (s *CommentService) Create(ctx context.Context, comment *Comment) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO comments (session_id, error_id, parent_id, author_id, body)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at, updated_at, author_id
+		)
+		SELECT i.id, i.created_at, i.updated_at, COALESCE(u.name, '')
+		FROM inserted i
+		JOIN users u ON u.id = i.author_id
+	`
+
+	return s.DB.Pool.QueryRow(
+		ctx,
+		query,
+		comment.SessionID,
+		comment.ErrorID,
+		comment.ParentID,
+		comment.AuthorID,
+		comment.Body,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt, &comment.AuthorName)
+}
+
+// Update changes the body of a comment
+func (s *CommentService) Update(ctx context.Context, comment *Comment) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE comments
+		SET body = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	return s.DB.Pool.QueryRow(ctx, query, comment.ID, comment.Body).Scan(&comment.UpdatedAt)
+}
+
+// Delete removes a comment together with its replies
+func (s *CommentService) Delete(ctx context.Context, id int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM comments WHERE id = $1`
+
+	_, err := s.DB.Pool.Exec(ctx, query, id)
+
+	return err
+}
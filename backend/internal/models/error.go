@@ -0,0 +1,318 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// Error stroke types, matching the error_strokes lookup table
+const (
+	StrokeForehand = "forehand"
+	StrokeBackhand = "backhand"
+	StrokeServe    = "serve"
+	StrokeVolley   = "volley"
+	StrokeOverhead = "overhead"
+	StrokeReturn   = "return"
+	StrokeDropShot = "drop_shot"
+)
+
+// Error sides, matching the error_sides lookup table
+const (
+	SideForehand = "forehand"
+	SideBackhand = "backhand"
+)
+
+// Error outcomes, matching the error_outcomes lookup table
+const (
+	OutcomeNet         = "net"
+	OutcomeLong        = "long"
+	OutcomeWide        = "wide"
+	OutcomeDoubleFault = "double_fault"
+)
+
+var (
+	// ErrorStrokes lists the valid stroke types
+	ErrorStrokes = []string{StrokeForehand, StrokeBackhand, StrokeServe, StrokeVolley, StrokeOverhead, StrokeReturn, StrokeDropShot}
+	// ErrorSides lists the valid sides
+	ErrorSides = []string{SideForehand, SideBackhand}
+	// ErrorOutcomes lists the valid outcomes
+	ErrorOutcomes = []string{OutcomeNet, OutcomeLong, OutcomeWide, OutcomeDoubleFault}
+)
+
+// ErrorEntry represents a batch of unforced errors logged for a session.
+// Categories are optional; entries logged before categorization have none.
+type ErrorEntry struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"session_id"`
+	ClientID   *string   `json:"client_id,omitempty"` // Set for entries created through sync
+	Count      int       `json:"count"`
+	Stroke     string    `json:"stroke,omitempty"`
+	Side       string    `json:"side,omitempty"`
+	Outcome    string    `json:"outcome,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"` // When the errors happened, as opposed to when they were saved
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CategoryError reports an invalid stroke, side or outcome of an error entry
+type CategoryError struct {
+	Field  string
+	Reason string
+}
+
+func (e *CategoryError) Error() string {
+	return e.Reason
+}
+
+// Invalid returns the error as shown to clients
+func (e *CategoryError) Invalid() *apperr.Error {
+	return apperr.Invalid(e.Field, strings.ToUpper(e.Reason[:1])+e.Reason[1:])
+}
+
+// ValidateCategories checks the stroke, side and outcome of the entry against
+// the known values and against each other. A forehand or backhand stroke
+// implies its side, which is filled in when missing.
+func (e *ErrorEntry) ValidateCategories() *CategoryError {
+	if e.Stroke != "" && !contains(ErrorStrokes, e.Stroke) {
+		return &CategoryError{"stroke", "invalid stroke"}
+	}
+	if e.Side != "" && !contains(ErrorSides, e.Side) {
+		return &CategoryError{"side", "invalid side"}
+	}
+	if e.Outcome != "" && !contains(ErrorOutcomes, e.Outcome) {
+		return &CategoryError{"outcome", "invalid outcome"}
+	}
+
+	switch e.Stroke {
+	case StrokeForehand, StrokeBackhand:
+		if e.Side == "" {
+			e.Side = e.Stroke
+		} else if e.Side != e.Stroke {
+			return &CategoryError{"side", "side must match a forehand or backhand stroke"}
+		}
+	case StrokeServe:
+		if e.Side != "" {
+			return &CategoryError{"side", "a serve has no side"}
+		}
+	}
+
+	if e.Outcome == OutcomeDoubleFault && e.Stroke != StrokeServe {
+		return &CategoryError{"outcome", "a double fault must be a serve"}
+	}
+
+	return nil
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorService handles database operations for error entries
+type ErrorService struct {
+	DB *database.DB
+}
+
+// GetByID retrieves an error entry by ID
+func (s *ErrorService) GetByID(ctx context.Context, id int) (*ErrorEntry, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var entry ErrorEntry
+
+	query := `
+		SELECT id, session_id, client_id, count, COALESCE(stroke, ''), COALESCE(side, ''), COALESCE(outcome, ''),
+		       recorded_at, created_at, updated_at
+		FROM errors
+		WHERE id = $1
+	`
+
+	err := s.DB.Pool.QueryRow(ctx, query, id).Scan(
+		&entry.ID,
+		&entry.SessionID,
+		&entry.ClientID,
+		&entry.Count,
+		&entry.Stroke,
+		&entry.Side,
+		&entry.Outcome,
+		&entry.RecordedAt,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// GetBySessionID retrieves all error entries for a session in the order they happened
+func (s *ErrorService) GetBySessionID(ctx context.Context, sessionID int) ([]ErrorEntry, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var entries []ErrorEntry
+
+	query := `
+		SELECT id, session_id, client_id, count, COALESCE(stroke, ''), COALESCE(side, ''), COALESCE(outcome, ''),
+		       recorded_at, created_at, updated_at
+		FROM errors
+		WHERE session_id = $1
+		ORDER BY recorded_at, id
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry ErrorEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.SessionID,
+			&entry.ClientID,
+			&entry.Count,
+			&entry.Stroke,
+			&entry.Side,
+			&entry.Outcome,
+			&entry.RecordedAt,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Create inserts a new error entry into the database. A zero RecordedAt
+// defaults to the current time.
+func (s *ErrorService) Create(ctx context.Context, entry *ErrorEntry) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	userID, err := sessionOwner(ctx, s.DB.Pool, entry.SessionID)
+	if err != nil {
+		return err
+	}
+
+	return withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		return createErrorEntry(ctx, tx, userID, entry)
+	})
+}
+
+// createErrorEntry implements Create inside a transaction recording changes
+// of the user owning the entry's session
+func createErrorEntry(ctx context.Context, tx pgx.Tx, userID int, entry *ErrorEntry) error {
+	query := `
+		INSERT INTO errors (session_id, count, stroke, side, outcome, recorded_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), COALESCE($6, NOW()))
+		RETURNING id, recorded_at, created_at, updated_at
+	`
+
+	var recordedAt *time.Time
+	if !entry.RecordedAt.IsZero() {
+		recordedAt = &entry.RecordedAt
+	}
+
+	err := tx.QueryRow(
+		ctx,
+		query,
+		entry.SessionID,
+		entry.Count,
+		entry.Stroke,
+		entry.Side,
+		entry.Outcome,
+		recordedAt,
+	).Scan(&entry.ID, &entry.RecordedAt, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return recordChange(ctx, tx, userID, ChangeEntityError, entry.ID, entry.ClientID, false)
+}
+
+// Update updates an existing error entry
+func (s *ErrorService) Update(ctx context.Context, entry *ErrorEntry) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	userID, err := sessionOwner(ctx, s.DB.Pool, entry.SessionID)
+	if err != nil {
+		return err
+	}
+
+	return withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		query := `
+			UPDATE errors
+			SET count = $2, stroke = NULLIF($3, ''), side = NULLIF($4, ''), outcome = NULLIF($5, ''),
+			    recorded_at = $6, updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at
+		`
+
+		err := tx.QueryRow(
+			ctx,
+			query,
+			entry.ID,
+			entry.Count,
+			entry.Stroke,
+			entry.Side,
+			entry.Outcome,
+			entry.RecordedAt,
+		).Scan(&entry.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, userID, ChangeEntityError, entry.ID, entry.ClientID, false)
+	})
+}
+
+// Delete removes an error entry from the database, leaving a tombstone in the
+// change log
+func (s *ErrorService) Delete(ctx context.Context, id int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var userID int
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT s.user_id
+		FROM errors e
+		JOIN sessions s ON s.id = e.session_id
+		WHERE e.id = $1
+	`, id).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		_, err := changeRows(ctx, tx, userID, ChangeEntityError, true,
+			`DELETE FROM errors WHERE id = $1 RETURNING id, client_id`, id)
+		return err
+	})
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// SessionWithErrors is a session together with all of its error entries
+type SessionWithErrors struct {
+	Session
+	Errors []ErrorEntry `json:"errors"`
+}
+
+// Export streams every session of a user with its error entries, oldest
+// first, calling fn once per session. Rows are read from the database one at
+// a time so only the current session is held in memory, and fn must not keep
+// the session after it returns. Iteration stops at the first error returned by
+// fn, or when ctx is cancelled.
+func (s *SessionService) Export(ctx context.Context, userID int, fn func(session *SessionWithErrors) error) error {
+	query := `
+		SELECT s.id, s.user_id, s.client_id, s.name, s.opponent_id, s.opponent_name, s.session_date, s.created_at, s.updated_at,
+		       e.id, e.client_id, e.count, COALESCE(e.stroke, ''), COALESCE(e.side, ''), COALESCE(e.outcome, ''),
+		       e.recorded_at, e.created_at, e.updated_at
+		FROM sessions s
+		LEFT JOIN errors e ON s.id = e.session_id
+		WHERE s.user_id = $1
+		ORDER BY s.session_date, s.id, e.recorded_at, e.id
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var current SessionWithErrors
+	started := false
+
+	for rows.Next() {
+		var (
+			session    Session
+			entryID    *int
+			entry      ErrorEntry
+			count      *int
+			recordedAt *time.Time
+			createdAt  *time.Time
+			updatedAt  *time.Time
+		)
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.ClientID,
+			&session.Name,
+			&session.OpponentID,
+			&session.OpponentName,
+			&session.SessionDate,
+			&session.CreatedAt,
+			&session.UpdatedAt,
+			&entryID,
+			&entry.ClientID,
+			&count,
+			&entry.Stroke,
+			&entry.Side,
+			&entry.Outcome,
+			&recordedAt,
+			&createdAt,
+			&updatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		if !started || current.ID != session.ID {
+			if started {
+				if err := fn(&current); err != nil {
+					return err
+				}
+			}
+			current = SessionWithErrors{Session: session, Errors: []ErrorEntry{}}
+			started = true
+		}
+
+		// Sessions without error entries come back with NULL error columns
+		if entryID == nil {
+			continue
+		}
+
+		entry.ID = *entryID
+		entry.SessionID = session.ID
+		entry.Count = *count
+		entry.RecordedAt = *recordedAt
+		entry.CreatedAt = *createdAt
+		entry.UpdatedAt = *updatedAt
+
+		current.Errors = append(current.Errors, entry)
+		current.ErrorCount += entry.Count
+		current.AddCategoryTotals(entry.Stroke, entry.Side, entry.Outcome, entry.Count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if started {
+		return fn(&current)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// MaxImportSessions is the most sessions a single import may create
+const MaxImportSessions = 5000
+
+// Import creates the given sessions of a user and their error entries in a
+// single transaction, so either all of them are stored or none. Opponents are
+// matched by name and created on first use, as for new sessions. IDs and
+// timestamps are filled in on success.
+func (s *SessionService) Import(ctx context.Context, userID int, sessions []SessionWithErrors) error {
+	// Large imports may take a while, so only the request bounds this call
+	return withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		for i := range sessions {
+			session := &sessions[i]
+			session.UserID = userID
+
+			if name := NormalizeOpponentName(session.OpponentName); name != "" {
+				opponent, err := findOrCreateOpponent(ctx, tx, userID, name)
+				if err != nil {
+					return err
+				}
+				session.OpponentID = &opponent.ID
+				session.OpponentName = opponent.Name
+			}
+
+			if err := createSession(ctx, tx, &session.Session); err != nil {
+				return err
+			}
+
+			for j := range session.Errors {
+				entry := &session.Errors[j]
+				entry.SessionID = session.ID
+				if err := createErrorEntry(ctx, tx, userID, entry); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// ErrOpponentExists is returned when renaming an opponent to a name another opponent already uses
+var ErrOpponentExists = errors.New("opponent already exists")
+
+// Opponent represents someone a user has played against
+type Opponent struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	Name         string    `json:"name"`
+	SessionCount int       `json:"session_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OpponentService handles database operations for opponents
+type OpponentService struct {
+	DB *database.DB
+}
+
+// NormalizeOpponentName trims and collapses whitespace in an opponent name
+func NormalizeOpponentName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// GetByID retrieves an opponent by ID
+func (s *OpponentService) GetByID(ctx context.Context, id int) (*Opponent, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var opponent Opponent
+
+	query := `
+		SELECT o.id, o.user_id, o.name, COUNT(s.id), o.created_at, o.updated_at
+		FROM opponents o
+		LEFT JOIN sessions s ON s.opponent_id = o.id
+		WHERE o.id = $1
+		GROUP BY o.id
+	`
+
+	err := s.DB.Pool.QueryRow(ctx, query, id).Scan(
+		&opponent.ID,
+		&opponent.UserID,
+		&opponent.Name,
+		&opponent.SessionCount,
+		&opponent.CreatedAt,
+		&opponent.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &opponent, nil
+}
+
+// GetByUserID retrieves all opponents of a user, alphabetically
+func (s *OpponentService) GetByUserID(ctx context.Context, userID int) ([]Opponent, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var opponents []Opponent
+
+	query := `
+		SELECT o.id, o.user_id, o.name, COUNT(s.id), o.created_at, o.updated_at
+		FROM opponents o
+		LEFT JOIN sessions s ON s.opponent_id = o.id
+		WHERE o.user_id = $1
+		GROUP BY o.id
+		ORDER BY LOWER(o.name)
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var opponent Opponent
+		err := rows.Scan(
+			&opponent.ID,
+			&opponent.UserID,
+			&opponent.Name,
+			&opponent.SessionCount,
+			&opponent.CreatedAt,
+			&opponent.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		opponents = append(opponents, opponent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return opponents, nil
+}
+
+// FindOrCreate returns the opponent of a user matching name case-insensitively,
+// creating it if there is none
+func (s *OpponentService) FindOrCreate(ctx context.Context, userID int, name string) (*Opponent, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	return findOrCreateOpponent(ctx, s.DB.Pool, userID, name)
+}
+
+// findOrCreateOpponent implements FindOrCreate on a pool or transaction
+func findOrCreateOpponent(ctx context.Context, q querier, userID int, name string) (*Opponent, error) {
+	opponent := Opponent{UserID: userID}
+
+	// The no-op update makes RETURNING yield the existing row on conflict
+	query := `
+		INSERT INTO opponents (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, LOWER(name)) DO UPDATE SET name = opponents.name
+		RETURNING id, name, created_at, updated_at
+	`
+
+	err := q.QueryRow(
+		ctx,
+		query,
+		userID,
+		NormalizeOpponentName(name),
+	).Scan(&opponent.ID, &opponent.Name, &opponent.CreatedAt, &opponent.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &opponent, nil
+}
+
+// Rename changes the name of an opponent and of all sessions played against it
+func (s *OpponentService) Rename(ctx context.Context, opponent *Opponent, name string) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockChanges(ctx, tx, opponent.UserID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE opponents
+		SET name = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING name, updated_at
+	`, opponent.ID, NormalizeOpponentName(name)).Scan(&opponent.Name, &opponent.UpdatedAt)
+	if isUniqueViolation(err, "idx_opponents_user_id_lower_name") {
+		return ErrOpponentExists
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = changeRows(ctx, tx, opponent.UserID, ChangeEntitySession, false, `
+		UPDATE sessions
+		SET opponent_name = $2, updated_at = NOW()
+		WHERE opponent_id = $1
+		RETURNING id, client_id
+	`, opponent.ID, opponent.Name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Merge moves all sessions of the source opponents to the target opponent and
+// deletes the sources. Sources that do not belong to the target's user are ignored.
+func (s *OpponentService) Merge(ctx context.Context, target *Opponent, sourceIDs []int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockChanges(ctx, tx, target.UserID); err != nil {
+		return err
+	}
+
+	_, err = changeRows(ctx, tx, target.UserID, ChangeEntitySession, false, `
+		UPDATE sessions
+		SET opponent_id = $1, opponent_name = $2, updated_at = NOW()
+		WHERE opponent_id = ANY($3) AND user_id = $4 AND opponent_id <> $1
+		RETURNING id, client_id
+	`, target.ID, target.Name, sourceIDs, target.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM opponents
+		WHERE id = ANY($1) AND user_id = $2 AND id <> $3
+	`, sourceIDs, target.UserID, target.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// PasswordResetTTL is how long a password reset link stays valid
+const PasswordResetTTL = time.Hour
+
+// resetTokenBytes is the amount of randomness in a password reset token
+const resetTokenBytes = 32
+
+// ErrResetTokenInvalid is returned for unknown, expired or already used reset tokens
+var ErrResetTokenInvalid = errors.New("invalid or expired reset token")
+
+// PasswordResetService handles database operations for password reset tokens
+type PasswordResetService struct {
+	DB *database.DB
+}
+
+// Create issues a new reset token for a user, replacing any unused ones, and
+// returns the plaintext token to send to the user
+func (s *PasswordResetService) Create(ctx context.Context, userID int) (string, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	token, err := RandomToken(resetTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// Only the most recent link works
+	if _, err := tx.Exec(ctx, `
+		UPDATE password_resets
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO password_resets (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, HashToken(token), time.Now().Add(PasswordResetTTL)); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Reset consumes a reset token, sets the password hash of the user it was
+// issued to and revokes all their tokens. Either all of it happens or none,
+// so a failure leaves the token usable. A token can only be used once.
+func (s *PasswordResetService) Reset(ctx context.Context, token, passwordHash string) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		UPDATE password_resets
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, HashToken(token)).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2, updated_at = NOW()
+		WHERE id = $1
+	`, userID, passwordHash); err != nil {
+		return err
+	}
+
+	// Log out every existing session
+	if err := revokeAllForUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/scoring"
+)
+
+// ErrPointConflict is returned when another point was recorded for the session concurrently
+var ErrPointConflict = errors.New("point sequence conflict")
+
+// Point represents a single point played in a session
+type Point struct {
+	ID        int                `json:"id"`
+	SessionID int                `json:"session_id"`
+	Seq       int                `json:"seq"` // 1-based position of the point in the match
+	Winner    scoring.Competitor `json:"winner"`
+	Ending    scoring.Ending     `json:"ending"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// PointService handles database operations for match formats and points
+type PointService struct {
+	DB *database.DB
+}
+
+// GetFormat retrieves the match format of a session, or the default format if none was set
+func (s *PointService) GetFormat(ctx context.Context, sessionID int) (scoring.Format, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	format := scoring.DefaultFormat()
+
+	query := `
+		SELECT best_of, no_ad, match_tiebreak, first_server, games_per_set, tiebreak_points, match_tb_points
+		FROM match_formats
+		WHERE session_id = $1
+	`
+
+	var firstServer string
+	err := s.DB.Pool.QueryRow(ctx, query, sessionID).Scan(
+		&format.BestOf,
+		&format.NoAd,
+		&format.MatchTiebreak,
+		&firstServer,
+		&format.GamesPerSet,
+		&format.TiebreakPoints,
+		&format.MatchTBPoints,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return scoring.DefaultFormat(), nil
+	}
+	if err != nil {
+		return format, err
+	}
+
+	format.FirstServer, err = scoring.ParseCompetitor(firstServer)
+	return format, err
+}
+
+// SetFormat creates or replaces the match format of a session
+func (s *PointService) SetFormat(ctx context.Context, sessionID int, format scoring.Format) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO match_formats (session_id, best_of, no_ad, match_tiebreak, first_server, games_per_set, tiebreak_points, match_tb_points)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (session_id) DO UPDATE
+		SET best_of = EXCLUDED.best_of, no_ad = EXCLUDED.no_ad, match_tiebreak = EXCLUDED.match_tiebreak,
+		    first_server = EXCLUDED.first_server, games_per_set = EXCLUDED.games_per_set,
+		    tiebreak_points = EXCLUDED.tiebreak_points, match_tb_points = EXCLUDED.match_tb_points,
+		    updated_at = NOW()
+	`
+
+	_, err := s.DB.Pool.Exec(
+		ctx,
+		query,
+		sessionID,
+		format.BestOf,
+		format.NoAd,
+		format.MatchTiebreak,
+		format.FirstServer.String(),
+		format.GamesPerSet,
+		format.TiebreakPoints,
+		format.MatchTBPoints,
+	)
+
+	return err
+}
+
+// GetBySessionID retrieves all points of a session in the order they were played
+func (s *PointService) GetBySessionID(ctx context.Context, sessionID int) ([]Point, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var points []Point
+
+	query := `
+		SELECT id, session_id, seq, winner, ending, created_at
+		FROM points
+		WHERE session_id = $1
+		ORDER BY seq
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			point          Point
+			winner, ending string
+		)
+		err := rows.Scan(
+			&point.ID,
+			&point.SessionID,
+			&point.Seq,
+			&winner,
+			&ending,
+			&point.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if point.Winner, err = scoring.ParseCompetitor(winner); err != nil {
+			return nil, err
+		}
+		point.Ending = scoring.Ending(ending)
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// Create inserts a point at the given sequence number. If another point took
+// that position in the meantime ErrPointConflict is returned.
+func (s *PointService) Create(ctx context.Context, point *Point) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO points (session_id, seq, winner, ending)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := s.DB.Pool.QueryRow(
+		ctx,
+		query,
+		point.SessionID,
+		point.Seq,
+		point.Winner.String(),
+		string(point.Ending),
+	).Scan(&point.ID, &point.CreatedAt)
+
+	if isUniqueViolation(err, "points_session_id_seq_key") {
+		return ErrPointConflict
+	}
+
+	return err
+}
+
+// DeleteLast removes the most recent point of a session and reports whether there was one
+func (s *PointService) DeleteLast(ctx context.Context, sessionID int) (bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		DELETE FROM points
+		WHERE id = (SELECT id FROM points WHERE session_id = $1 ORDER BY seq DESC LIMIT 1)
+	`
+
+	tag, err := s.DB.Pool.Exec(ctx, query, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ScoringPoints returns the winners and endings of the points, for replaying through the scoring engine
+func ScoringPoints(points []Point) []scoring.Point {
+	played := make([]scoring.Point, len(points))
+	for i, p := range points {
+		played[i] = scoring.Point{Winner: p.Winner, Ending: p.Ending}
+	}
+	return played
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// RateLimitService handles database operations for rate limit buckets and
+// failed login counts
+type RateLimitService struct {
+	DB *database.DB
+}
+
+// Take takes a token from the bucket under key. It returns zero if one was
+// available, or else how long until one will be.
+func (s *RateLimitService) Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	// Taking a token pushes the time the bucket is full again back by one
+	// interval, unless that would be more than the whole bucket away
+	tag, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO rate_limit_buckets AS b (key, full_at)
+		VALUES ($1, NOW() + $2::float8 * INTERVAL '1 second')
+		ON CONFLICT (key) DO UPDATE
+		SET full_at = GREATEST(b.full_at, NOW()) + $2::float8 * INTERVAL '1 second'
+		WHERE GREATEST(b.full_at, NOW()) + $2::float8 * INTERVAL '1 second'
+			<= NOW() + $3::float8 * INTERVAL '1 second'
+	`, key, limit.Every.Seconds(), (time.Duration(limit.Burst) * limit.Every).Seconds())
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 1 {
+		return 0, nil
+	}
+
+	var backlog float64
+	err = s.DB.Pool.QueryRow(ctx, `
+		SELECT EXTRACT(EPOCH FROM GREATEST(full_at, NOW()) - NOW())::float8
+		FROM rate_limit_buckets
+		WHERE key = $1
+	`, key).Scan(&backlog)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The bucket filled up in the meantime
+		return limit.Every, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	wait := limit.Wait(seconds(backlog))
+	if wait == 0 {
+		wait = limit.Every
+	}
+	return wait, nil
+}
+
+// LockedOut returns how much longer key is locked out, or zero if it is not
+func (s *RateLimitService) LockedOut(ctx context.Context, key string) (time.Duration, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var remaining float64
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT EXTRACT(EPOCH FROM locked_until - NOW())::float8
+		FROM login_failures
+		WHERE key = $1 AND locked_until > NOW()
+	`, key).Scan(&remaining)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return seconds(remaining), nil
+}
+
+// RecordFailure counts a failed attempt for key and returns how long key is
+// now locked out under the policy, or zero if it is not
+func (s *RateLimitService) RecordFailure(ctx context.Context, key string, policy ratelimit.LockoutPolicy) (time.Duration, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var failures int
+	err := s.DB.Pool.QueryRow(ctx, `
+		INSERT INTO login_failures AS f (key, failures, last_failed_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN f.last_failed_at < NOW() - $2::float8 * INTERVAL '1 second' THEN 1
+				ELSE f.failures + 1
+			END,
+			last_failed_at = NOW()
+		RETURNING failures
+	`, key, policy.Reset.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+
+	lockout := policy.Duration(failures)
+	if lockout == 0 {
+		return 0, nil
+	}
+
+	_, err = s.DB.Pool.Exec(ctx, `
+		UPDATE login_failures
+		SET locked_until = NOW() + $2::float8 * INTERVAL '1 second'
+		WHERE key = $1
+	`, key, lockout.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// ClearFailures forgets the failed attempts of key, e.g. after a successful login
+func (s *RateLimitService) ClearFailures(ctx context.Context, key string) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := s.DB.Pool.Exec(ctx, `DELETE FROM login_failures WHERE key = $1`, key)
+	return err
+}
+
+// Sweep deletes the buckets that have filled up again, which are the same as
+// none, and the failures forgotten after reset whose lockout has passed
+func (s *RateLimitService) Sweep(ctx context.Context, reset time.Duration) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.DB.Pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE full_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := s.DB.Pool.Exec(ctx, `
+		DELETE FROM login_failures
+		WHERE last_failed_at < NOW() - $1::float8 * INTERVAL '1 second'
+			AND (locked_until IS NULL OR locked_until < NOW())
+	`, reset.Seconds())
+	return err
+}
+
+// seconds converts a number of seconds read from Postgres to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -2,8 +2,15 @@ package models
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
 )
 
@@ -11,12 +18,19 @@ import (
 type Session struct {
 	ID           int       `json:"id"`
 	UserID       int       `json:"user_id"`
+	ClientID     *string   `json:"client_id,omitempty"` // Set for sessions created through sync
 	Name         string    `json:"name"`
+	OpponentID   *int      `json:"opponent_id,omitempty"`
 	OpponentName string    `json:"opponent_name,omitempty"`
 	SessionDate  time.Time `json:"session_date"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	ErrorCount   int       `json:"error_count,omitempty"` // Total errors for this session
+
+	// Per-category totals; uncategorized errors only count towards ErrorCount
+	ErrorsByStroke  map[string]int `json:"errors_by_stroke,omitempty"`
+	ErrorsBySide    map[string]int `json:"errors_by_side,omitempty"`
+	ErrorsByOutcome map[string]int `json:"errors_by_outcome,omitempty"`
 }
 
 // SessionService handles database operations for sessions
@@ -25,11 +39,14 @@ type SessionService struct {
 }
 
 // GetByID retrieves a session by ID
-func (s *SessionService) GetByID(id int) (*Session, error) {
+func (s *SessionService) GetByID(ctx context.Context, id int) (*Session, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	var session Session
 	
 	query := `
-		SELECT s.id, s.user_id, s.name, s.opponent_name, s.session_date, s.created_at, s.updated_at,
+		SELECT s.id, s.user_id, s.client_id, s.name, s.opponent_id, s.opponent_name, s.session_date, s.created_at, s.updated_at,
 		       COALESCE(SUM(e.count), 0) as error_count
 		FROM sessions s
 		LEFT JOIN errors e ON s.id = e.session_id
@@ -37,10 +54,12 @@ func (s *SessionService) GetByID(id int) (*Session, error) {
 		GROUP BY s.id
 	`
 	
-	err := s.DB.Pool.QueryRow(context.Background(), query, id).Scan(
+	err := s.DB.Pool.QueryRow(ctx, query, id).Scan(
 		&session.ID,
 		&session.UserID,
+		&session.ClientID,
 		&session.Name,
+		&session.OpponentID,
 		&session.OpponentName,
 		&session.SessionDate,
 		&session.CreatedAt,
@@ -52,35 +71,145 @@ func (s *SessionService) GetByID(id int) (*Session, error) {
 		return nil, err
 	}
 	
+	if err := s.loadCategoryTotals(ctx, []*Session{&session}); err != nil {
+		return nil, err
+	}
+	
 	return &session, nil
 }
 
-// GetByUserID retrieves all sessions for a user
-func (s *SessionService) GetByUserID(userID int) ([]Session, error) {
+// Session list sort keys
+const (
+	SessionSortDate   = "date"
+	SessionSortErrors = "errors"
+	SessionSortName   = "name"
+)
+
+// Session list page sizes
+const (
+	DefaultSessionPageSize = 50
+	MaxSessionPageSize     = 200
+)
+
+// SessionListOptions selects a page of a user's sessions
+type SessionListOptions struct {
+	Filter SessionFilter
+	Sort   string // One of the SessionSort keys, by date when empty
+	Desc   bool
+	Limit  int
+	After  *SessionCursor // Continue after this cursor; nil for the first page
+}
+
+// SessionCursor marks the last session of a page: its value of the sort key
+// and its ID, which breaks ties. It is only valid for the sort it was made for.
+type SessionCursor struct {
+	Sort   string    `json:"s"`
+	Desc   bool      `json:"d,omitempty"`
+	Date   time.Time `json:"t,omitempty"`
+	Errors int       `json:"e,omitempty"`
+	Name   string    `json:"n,omitempty"`
+	ID     int       `json:"i"`
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *SessionCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseSessionCursor decodes a cursor made by Encode
+func ParseSessionCursor(value string) (*SessionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var cursor SessionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// List retrieves a page of the sessions of a user matching the filter, in
+// the requested order, using keyset pagination on the sort key and the ID.
+// The returned cursor points after the last session and is nil on the last
+// page.
+func (s *SessionService) List(ctx context.Context, userID int, opts SessionListOptions) ([]Session, *SessionCursor, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	var sessions []Session
-	
+
+	if opts.Limit <= 0 || opts.Limit > MaxSessionPageSize {
+		opts.Limit = DefaultSessionPageSize
+	}
+
+	var key string
+	switch opts.Sort {
+	case SessionSortErrors:
+		key = "page.error_count"
+	case SessionSortName:
+		key = "LOWER(page.name)"
+	default:
+		opts.Sort = SessionSortDate
+		key = "page.session_date"
+	}
+
+	direction, compare := "ASC", ">"
+	if opts.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	conds, args := opts.Filter.where([]interface{}{userID})
+	having, args := opts.Filter.having(args)
+
+	keyset := ""
+	if after := opts.After; after != nil {
+		var value interface{}
+		switch opts.Sort {
+		case SessionSortErrors:
+			value = after.Errors
+		case SessionSortName:
+			value = after.Name
+		default:
+			value = after.Date
+		}
+		args = append(args, value, after.ID)
+		keyset = fmt.Sprintf("WHERE (%s, page.id) %s ($%d, $%d)", key, compare, len(args)-1, len(args))
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	args = append(args, opts.Limit+1)
 	query := `
-		SELECT s.id, s.user_id, s.name, s.opponent_name, s.session_date, s.created_at, s.updated_at,
-		       COALESCE(SUM(e.count), 0) as error_count
-		FROM sessions s
-		LEFT JOIN errors e ON s.id = e.session_id
-		WHERE s.user_id = $1
-		GROUP BY s.id
-		ORDER BY s.session_date DESC
-	`
-	
-	rows, err := s.DB.Pool.Query(context.Background(), query, userID)
+		SELECT page.id, page.user_id, page.client_id, page.name, page.opponent_id, page.opponent_name,
+		       page.session_date, page.created_at, page.updated_at, page.error_count
+		FROM (
+			SELECT s.id, s.user_id, s.client_id, s.name, s.opponent_id, s.opponent_name, s.session_date, s.created_at, s.updated_at,
+			       COALESCE(SUM(e.count), 0) as error_count
+			FROM sessions s
+			LEFT JOIN errors e ON s.id = e.session_id
+			WHERE s.user_id = $1` + conds + `
+			GROUP BY s.id` + having + `
+		) page
+		` + keyset + `
+		ORDER BY ` + key + ` ` + direction + `, page.id ` + direction + `
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := s.DB.Pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
-	
+
 	for rows.Next() {
 		var session Session
 		err := rows.Scan(
 			&session.ID,
 			&session.UserID,
+			&session.ClientID,
 			&session.Name,
+			&session.OpponentID,
 			&session.OpponentName,
 			&session.SessionDate,
 			&session.CreatedAt,
@@ -88,64 +217,309 @@ func (s *SessionService) GetByUserID(userID int) ([]Session, error) {
 			&session.ErrorCount,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		sessions = append(sessions, session)
 	}
-	
+
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	
-	return sessions, nil
+
+	var next *SessionCursor
+	if len(sessions) > opts.Limit {
+		sessions = sessions[:opts.Limit]
+		last := sessions[len(sessions)-1]
+		next = &SessionCursor{Sort: opts.Sort, Desc: opts.Desc, ID: last.ID}
+		switch opts.Sort {
+		case SessionSortErrors:
+			next.Errors = last.ErrorCount
+		case SessionSortName:
+			next.Name = strings.ToLower(last.Name)
+		default:
+			next.Date = last.SessionDate
+		}
+	}
+
+	refs := make([]*Session, len(sessions))
+	for i := range sessions {
+		refs[i] = &sessions[i]
+	}
+	if err := s.loadCategoryTotals(ctx, refs); err != nil {
+		return nil, nil, err
+	}
+
+	return sessions, next, nil
 }
 
 // Create inserts a new session into the database
-func (s *SessionService) Create(session *Session) error {
+func (s *SessionService) Create(ctx context.Context, session *Session) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	return withChanges(ctx, s.DB, session.UserID, func(tx pgx.Tx) error {
+		return createSession(ctx, tx, session)
+	})
+}
+
+// createSession implements Create inside a transaction recording changes
+func createSession(ctx context.Context, tx pgx.Tx, session *Session) error {
 	query := `
-		INSERT INTO sessions (user_id, name, opponent_name, session_date)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO sessions (user_id, name, opponent_id, opponent_name, session_date)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at, updated_at
 	`
-	
-	err := s.DB.Pool.QueryRow(
-		context.Background(),
+
+	err := tx.QueryRow(
+		ctx,
 		query,
 		session.UserID,
 		session.Name,
+		session.OpponentID,
 		session.OpponentName,
 		session.SessionDate,
 	).Scan(&session.ID, &session.CreatedAt, &session.UpdatedAt)
-	
-	return err
+	if err != nil {
+		return err
+	}
+
+	return recordChange(ctx, tx, session.UserID, ChangeEntitySession, session.ID, session.ClientID, false)
 }
 
 // Update updates an existing session
-func (s *SessionService) Update(session *Session) error {
+func (s *SessionService) Update(ctx context.Context, session *Session) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	return withChanges(ctx, s.DB, session.UserID, func(tx pgx.Tx) error {
+		query := `
+			UPDATE sessions
+			SET name = $2, opponent_id = $3, opponent_name = $4, session_date = $5, updated_at = NOW()
+			WHERE id = $1
+			RETURNING updated_at
+		`
+
+		err := tx.QueryRow(
+			ctx,
+			query,
+			session.ID,
+			session.Name,
+			session.OpponentID,
+			session.OpponentName,
+			session.SessionDate,
+		).Scan(&session.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, session.UserID, ChangeEntitySession, session.ID, session.ClientID, false)
+	})
+}
+
+// Delete removes a session and its error entries and share links from the
+// database, leaving tombstones in the change log
+func (s *SessionService) Delete(ctx context.Context, id int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	userID, err := sessionOwner(ctx, s.DB.Pool, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		return deleteSession(ctx, tx, userID, id)
+	})
+}
+
+// loadCategoryTotals fills in the per-category error totals of the given sessions
+func (s *SessionService) loadCategoryTotals(ctx context.Context, sessions []*Session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Session, len(sessions))
+	ids := make([]int, 0, len(sessions))
+	for _, session := range sessions {
+		byID[session.ID] = session
+		ids = append(ids, session.ID)
+	}
+
 	query := `
-		UPDATE sessions
-		SET name = $2, opponent_name = $3, session_date = $4, updated_at = NOW()
-		WHERE id = $1
-		RETURNING updated_at
+		SELECT session_id, COALESCE(stroke, ''), COALESCE(side, ''), COALESCE(outcome, ''), SUM(count)
+		FROM errors
+		WHERE session_id = ANY($1)
+		GROUP BY session_id, stroke, side, outcome
 	`
-	
-	err := s.DB.Pool.QueryRow(
-		context.Background(),
-		query,
-		session.ID,
-		session.Name,
-		session.OpponentName,
-		session.SessionDate,
-	).Scan(&session.UpdatedAt)
-	
-	return err
+
+	rows, err := s.DB.Pool.Query(ctx, query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			sessionID             int
+			stroke, side, outcome string
+			count                 int
+		)
+		if err := rows.Scan(&sessionID, &stroke, &side, &outcome, &count); err != nil {
+			return err
+		}
+
+		byID[sessionID].AddCategoryTotals(stroke, side, outcome, count)
+	}
+
+	return rows.Err()
 }
 
-// Delete removes a session from the database
-func (s *SessionService) Delete(id int) error {
-	query := `DELETE FROM sessions WHERE id = $1`
-	
-	_, err := s.DB.Pool.Exec(context.Background(), query, id)
-	
-	return err
+// AddCategoryTotals adds count errors of the given stroke, side and outcome
+// to the totals per category
+func (s *Session) AddCategoryTotals(stroke, side, outcome string, count int) {
+	addCategoryTotal(&s.ErrorsByStroke, stroke, count)
+	addCategoryTotal(&s.ErrorsBySide, side, count)
+	addCategoryTotal(&s.ErrorsByOutcome, outcome, count)
+}
+
+// addCategoryTotal adds count to the total for category, ignoring uncategorized errors
+func addCategoryTotal(totals *map[string]int, category string, count int) {
+	if category == "" {
+		return
+	}
+	if *totals == nil {
+		*totals = make(map[string]int)
+	}
+	(*totals)[category] += count
+}
+
+// Session types. They are derived: a session is a match once points have
+// been logged for it, and a practice otherwise.
+const (
+	SessionTypeMatch    = "match"
+	SessionTypePractice = "practice"
+)
+
+// SessionFilter narrows down the sessions of a user. Zero values do not filter.
+type SessionFilter struct {
+	From       time.Time // Sessions on or after this time
+	To         time.Time // Sessions before this time
+	Opponent   string    // Case-insensitive opponent name
+	OpponentID int
+	Type       string // SessionTypeMatch or SessionTypePractice
+	MinErrors  *int   // Sessions with at least this many errors
+	MaxErrors  *int   // Sessions with at most this many errors
+}
+
+// where returns the SQL conditions and arguments for the filter, numbering
+// placeholders after the given number of existing arguments
+func (f SessionFilter) where(args []interface{}) (string, []interface{}) {
+	var conds []string
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if !f.From.IsZero() {
+		add("s.session_date >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("s.session_date < $%d", f.To)
+	}
+	if f.Opponent != "" {
+		add("LOWER(s.opponent_name) = LOWER($%d)", NormalizeOpponentName(f.Opponent))
+	}
+	if f.OpponentID != 0 {
+		add("s.opponent_id = $%d", f.OpponentID)
+	}
+	switch f.Type {
+	case SessionTypeMatch:
+		conds = append(conds, "EXISTS (SELECT 1 FROM points p WHERE p.session_id = s.id)")
+	case SessionTypePractice:
+		conds = append(conds, "NOT EXISTS (SELECT 1 FROM points p WHERE p.session_id = s.id)")
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " AND " + strings.Join(conds, " AND "), args
+}
+
+// having returns the HAVING clause and arguments for the error count bounds
+// of the filter, for queries grouping sessions with their errors as e
+func (f SessionFilter) having(args []interface{}) (string, []interface{}) {
+	var conds []string
+	if f.MinErrors != nil {
+		args = append(args, *f.MinErrors)
+		conds = append(conds, fmt.Sprintf("COALESCE(SUM(e.count), 0) >= $%d", len(args)))
+	}
+	if f.MaxErrors != nil {
+		args = append(args, *f.MaxErrors)
+		conds = append(conds, fmt.Sprintf("COALESCE(SUM(e.count), 0) <= $%d", len(args)))
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " HAVING " + strings.Join(conds, " AND "), args
+}
+
+// SessionTotal is the error total of a single session
+type SessionTotal struct {
+	SessionID    int       `json:"session_id"`
+	Name         string    `json:"name"`
+	OpponentName string    `json:"opponent_name,omitempty"`
+	SessionDate  time.Time `json:"session_date"`
+	ErrorCount   int       `json:"error_count"`
+}
+
+// GetTotalsByUserID retrieves the error total of every matching session of a
+// user, oldest first
+func (s *SessionService) GetTotalsByUserID(ctx context.Context, userID int, filter SessionFilter) ([]SessionTotal, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var totals []SessionTotal
+
+	conds, args := filter.where([]interface{}{userID})
+	having, args := filter.having(args)
+	query := `
+		SELECT s.id, s.name, COALESCE(s.opponent_name, ''), s.session_date,
+		       COALESCE(SUM(e.count), 0) as error_count
+		FROM sessions s
+		LEFT JOIN errors e ON s.id = e.session_id
+		WHERE s.user_id = $1` + conds + `
+		GROUP BY s.id` + having + `
+		ORDER BY s.session_date, s.id
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var total SessionTotal
+		err := rows.Scan(
+			&total.SessionID,
+			&total.Name,
+			&total.OpponentName,
+			&total.SessionDate,
+			&total.ErrorCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
 }
@@ -0,0 +1,207 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// Share link expiry limits
+const (
+	DefaultShareExpiry = 7 * 24 * time.Hour
+	MaxShareExpiry     = 90 * 24 * time.Hour
+)
+
+// shareTokenBytes is the amount of randomness in a share token
+const shareTokenBytes = 32
+
+// SharedLink represents a public, read-only link to a session
+type SharedLink struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	SessionID int       `json:"session_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Expired reports whether the link has passed its expiry time
+func (l *SharedLink) Expired() bool {
+	return !time.Now().Before(l.ExpiresAt)
+}
+
+// ShareService handles database operations for shared links
+type ShareService struct {
+	DB *database.DB
+}
+
+// RandomToken returns a URL-safe, cryptographically random token of n bytes
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create generates a new token for the link and inserts it into the database.
+// A zero or negative expiry defaults to DefaultShareExpiry.
+func (s *ShareService) Create(ctx context.Context, link *SharedLink, expiry time.Duration) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	if expiry <= 0 {
+		expiry = DefaultShareExpiry
+	}
+
+	token, err := RandomToken(shareTokenBytes)
+	if err != nil {
+		return err
+	}
+	link.Token = token
+	link.ExpiresAt = time.Now().Add(expiry)
+
+	return withChanges(ctx, s.DB, link.UserID, func(tx pgx.Tx) error {
+		query := `
+			INSERT INTO shared_links (user_id, session_id, token, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+		`
+
+		err := tx.QueryRow(
+			ctx,
+			query,
+			link.UserID,
+			link.SessionID,
+			link.Token,
+			link.ExpiresAt,
+		).Scan(&link.ID, &link.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, link.UserID, ChangeEntityShare, link.ID, nil, false)
+	})
+}
+
+// GetByToken retrieves a shared link by its token, whether or not it has expired
+func (s *ShareService) GetByToken(ctx context.Context, token string) (*SharedLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var link SharedLink
+
+	query := `
+		SELECT id, user_id, session_id, token, expires_at, created_at
+		FROM shared_links
+		WHERE token = $1
+	`
+
+	err := s.DB.Pool.QueryRow(ctx, query, token).Scan(
+		&link.ID,
+		&link.UserID,
+		&link.SessionID,
+		&link.Token,
+		&link.ExpiresAt,
+		&link.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetActiveBySessionID retrieves all unexpired links for a session, newest first
+func (s *ShareService) GetActiveBySessionID(ctx context.Context, sessionID int) ([]SharedLink, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var links []SharedLink
+
+	query := `
+		SELECT id, user_id, session_id, token, expires_at, created_at
+		FROM shared_links
+		WHERE session_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.DB.Pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link SharedLink
+		err := rows.Scan(
+			&link.ID,
+			&link.UserID,
+			&link.SessionID,
+			&link.Token,
+			&link.ExpiresAt,
+			&link.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Revoke removes a single link from a session and reports whether it existed
+func (s *ShareService) Revoke(ctx context.Context, sessionID int, token string) (bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	n, err := s.revoke(ctx, sessionID,
+		`DELETE FROM shared_links WHERE session_id = $1 AND token = $2 RETURNING id, NULL::uuid`,
+		sessionID, token)
+
+	return n > 0, err
+}
+
+// RevokeAll removes every link for a session and returns how many were removed
+func (s *ShareService) RevokeAll(ctx context.Context, sessionID int) (int64, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	n, err := s.revoke(ctx, sessionID,
+		`DELETE FROM shared_links WHERE session_id = $1 RETURNING id, NULL::uuid`,
+		sessionID)
+
+	return int64(n), err
+}
+
+// revoke runs a statement deleting links of a session, leaving tombstones in
+// the change log, and returns how many were deleted
+func (s *ShareService) revoke(ctx context.Context, sessionID int, sql string, args ...interface{}) (int, error) {
+	userID, err := sessionOwner(ctx, s.DB.Pool, sessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = withChanges(ctx, s.DB, userID, func(tx pgx.Tx) error {
+		n, err = changeRows(ctx, tx, userID, ChangeEntityShare, true, sql, args...)
+		return err
+	})
+
+	return n, err
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/ratelimit"
+)
+
+// The store interfaces below abstract the persistence of users, sessions and
+// the data handlers need around them. The services in this package implement
+// them on Postgres and package memory implements them in memory. Rows that do
+// not exist are reported as pgx.ErrNoRows by both.
+
+// UserStore stores user accounts
+type UserStore interface {
+	GetByID(ctx context.Context, id int) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	UpdateLastLogin(ctx context.Context, user *User) error
+}
+
+// SessionStore stores sessions and computes their error totals
+type SessionStore interface {
+	GetByID(ctx context.Context, id int) (*Session, error)
+	List(ctx context.Context, userID int, opts SessionListOptions) ([]Session, *SessionCursor, error)
+	Create(ctx context.Context, session *Session) error
+	Update(ctx context.Context, session *Session) error
+	Delete(ctx context.Context, id int) error
+	GetTotalsByUserID(ctx context.Context, userID int, filter SessionFilter) ([]SessionTotal, error)
+	Import(ctx context.Context, userID int, sessions []SessionWithErrors) error
+	Export(ctx context.Context, userID int, fn func(session *SessionWithErrors) error) error
+}
+
+// ErrorStore stores the error entries of sessions
+type ErrorStore interface {
+	GetByID(ctx context.Context, id int) (*ErrorEntry, error)
+	GetBySessionID(ctx context.Context, sessionID int) ([]ErrorEntry, error)
+	Create(ctx context.Context, entry *ErrorEntry) error
+	Update(ctx context.Context, entry *ErrorEntry) error
+	Delete(ctx context.Context, id int) error
+}
+
+// ShareStore stores public links to sessions
+type ShareStore interface {
+	Create(ctx context.Context, link *SharedLink, expiry time.Duration) error
+	GetByToken(ctx context.Context, token string) (*SharedLink, error)
+	GetActiveBySessionID(ctx context.Context, sessionID int) ([]SharedLink, error)
+	Revoke(ctx context.Context, sessionID int, token string) (bool, error)
+	RevokeAll(ctx context.Context, sessionID int) (int64, error)
+}
+
+// TokenStore stores refresh tokens and revoked access tokens
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, userID int) (string, error)
+	RotateRefreshToken(ctx context.Context, token string) (string, int, error)
+	RevokeFamily(ctx context.Context, userID int, token string) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID int) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// OpponentStore stores the opponents of users
+type OpponentStore interface {
+	GetByID(ctx context.Context, id int) (*Opponent, error)
+	GetByUserID(ctx context.Context, userID int) ([]Opponent, error)
+	FindOrCreate(ctx context.Context, userID int, name string) (*Opponent, error)
+	Rename(ctx context.Context, opponent *Opponent, name string) error
+	Merge(ctx context.Context, target *Opponent, sourceIDs []int) error
+}
+
+// CoachStore stores the links between coaches and their players
+type CoachStore interface {
+	GetByID(ctx context.Context, id int) (*CoachLink, error)
+	GetByCoachID(ctx context.Context, coachID int) ([]CoachLink, error)
+	GetByPlayerID(ctx context.Context, playerID int) ([]CoachLink, error)
+	Invite(ctx context.Context, coachID, playerID, invitedBy int) (*CoachLink, error)
+	Accept(ctx context.Context, link *CoachLink) error
+	Delete(ctx context.Context, id int) error
+	CanView(ctx context.Context, viewerID, ownerID int) (bool, error)
+}
+
+// The Postgres services implement the stores
+var (
+	_ UserStore       = (*UserService)(nil)
+	_ SessionStore    = (*SessionService)(nil)
+	_ ErrorStore      = (*ErrorService)(nil)
+	_ ShareStore      = (*ShareService)(nil)
+	_ TokenStore      = (*TokenService)(nil)
+	_ OpponentStore   = (*OpponentService)(nil)
+	_ CoachStore      = (*CoachService)(nil)
+	_ ratelimit.Store = (*RateLimitService)(nil)
+)
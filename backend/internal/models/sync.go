@@ -0,0 +1,503 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/apperr"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// MaxSyncMutations is the largest batch accepted by a single sync
+const MaxSyncMutations = 500
+
+// Entities that can be synced
+const (
+	SyncEntitySession = "session"
+	SyncEntityError   = "error"
+)
+
+// Sync operations
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+)
+
+// Sync result statuses
+const (
+	SyncStatusApplied   = "applied"   // The mutation was written
+	SyncStatusUnchanged = "unchanged" // The mutation had already been applied
+	SyncStatusConflict  = "conflict"  // The server has a newer version, which wins
+	SyncStatusRejected  = "rejected"  // The mutation is invalid and was skipped
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// SyncMutation is a change made by a client, possibly while offline. Rows are
+// identified by client-generated UUIDs, and UpdatedAt is when the change was
+// made on the client; conflicts are resolved last-writer-wins on it.
+type SyncMutation struct {
+	Entity    string       `json:"entity"`
+	Op        string       `json:"op"`
+	ClientID  string       `json:"client_id"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Session   *SyncSession `json:"session,omitempty"` // Fields of an upserted session
+	Error     *SyncError   `json:"error,omitempty"`   // Fields of an upserted error entry
+}
+
+// SyncSession holds the fields of an upserted session
+type SyncSession struct {
+	Name         string    `json:"name"`
+	OpponentName string    `json:"opponent_name"`
+	SessionDate  time.Time `json:"session_date"`
+}
+
+// SyncError holds the fields of an upserted error entry. Its session is given
+// by client ID, or by server ID for sessions that were created online.
+type SyncError struct {
+	SessionClientID string     `json:"session_client_id,omitempty"`
+	SessionID       int        `json:"session_id,omitempty"`
+	Count           int        `json:"count"`
+	Stroke          string     `json:"stroke,omitempty"`
+	Side            string     `json:"side,omitempty"`
+	Outcome         string     `json:"outcome,omitempty"`
+	RecordedAt      *time.Time `json:"recorded_at,omitempty"`
+}
+
+// SyncResult is the outcome of a single mutation. On success and on conflict
+// the server's version of the row is included, unless it was deleted.
+type SyncResult struct {
+	Entity   string `json:"entity"`
+	ClientID string `json:"client_id"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"` // Why the mutation was rejected
+	// Errors name the fields at fault when the mutation was rejected
+	Errors  []apperr.FieldError `json:"errors,omitempty"`
+	ID      int                 `json:"id,omitempty"` // Server ID of the row
+	Session *Session            `json:"session,omitempty"`
+	Entry   *ErrorEntry         `json:"entry,omitempty"`
+}
+
+// Validate normalizes the mutation and checks its fields. The error names
+// the field at fault.
+func (m *SyncMutation) Validate() *apperr.Error {
+	m.ClientID = strings.ToLower(strings.TrimSpace(m.ClientID))
+	if !uuidPattern.MatchString(m.ClientID) {
+		return apperr.Invalid("client_id", "Client ID must be a UUID")
+	}
+	if m.UpdatedAt.IsZero() {
+		return apperr.Invalid("updated_at", "Updated at is required")
+	}
+	if m.Op != SyncOpUpsert && m.Op != SyncOpDelete {
+		return apperr.Invalid("op", "Op must be upsert or delete")
+	}
+
+	switch m.Entity {
+	case SyncEntitySession:
+		if m.Op == SyncOpDelete {
+			return nil
+		}
+		if m.Session == nil {
+			return apperr.Invalid("session", "Session fields are required")
+		}
+		m.Session.Name = strings.TrimSpace(m.Session.Name)
+		m.Session.OpponentName = strings.TrimSpace(m.Session.OpponentName)
+		if m.Session.Name == "" {
+			return apperr.Invalid("session.name", "Session name is required")
+		}
+		if err := ValidateName(m.Session.Name); err != nil {
+			return apperr.Invalid("session.name", "Session name must be at most 255 characters without control characters")
+		}
+		if err := ValidateName(m.Session.OpponentName); err != nil {
+			return apperr.Invalid("session.opponent_name", "Opponent name must be at most 255 characters without control characters")
+		}
+		if m.Session.SessionDate.IsZero() {
+			return apperr.Invalid("session.session_date", "Session date is required")
+		}
+
+	case SyncEntityError:
+		if m.Op == SyncOpDelete {
+			return nil
+		}
+		if m.Error == nil {
+			return apperr.Invalid("error", "Error fields are required")
+		}
+		m.Error.SessionClientID = strings.ToLower(strings.TrimSpace(m.Error.SessionClientID))
+		if m.Error.SessionClientID == "" && m.Error.SessionID == 0 {
+			return apperr.Invalid("error.session_client_id", "Session client ID or session ID is required")
+		}
+		if m.Error.SessionClientID != "" && !uuidPattern.MatchString(m.Error.SessionClientID) {
+			return apperr.Invalid("error.session_client_id", "Session client ID must be a UUID")
+		}
+		if m.Error.Count < 1 {
+			return apperr.Invalid("error.count", "Count must be at least 1")
+		}
+		entry := ErrorEntry{Stroke: m.Error.Stroke, Side: m.Error.Side, Outcome: m.Error.Outcome}
+		if err := entry.ValidateCategories(); err != nil {
+			return apperr.Invalid("error."+err.Field, err.Invalid().Message)
+		}
+		m.Error.Side = entry.Side
+
+	default:
+		return apperr.Invalid("entity", "Entity must be session or error")
+	}
+
+	return nil
+}
+
+// reject marks the mutation as rejected for err
+func (r *SyncResult) reject(err *apperr.Error) {
+	r.Status = SyncStatusRejected
+	r.Message = err.Message
+	r.Errors = err.Fields
+}
+
+// SyncService applies batches of client mutations
+type SyncService struct {
+	DB *database.DB
+}
+
+// Apply applies the mutations of a user in order, in a single transaction, and
+// returns a result per mutation along with the change feed cursor after the
+// batch. Replaying a batch is harmless: mutations already applied
+// are reported as unchanged. A mutation older than the row on the server is
+// not applied and is reported as a conflict. Invalid mutations are rejected
+// without affecting the rest of the batch.
+func (s *SyncService) Apply(ctx context.Context, userID int, mutations []SyncMutation) ([]SyncResult, string, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockChanges(ctx, tx, userID); err != nil {
+		return nil, "", err
+	}
+
+	var now time.Time
+	if err := tx.QueryRow(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return nil, "", err
+	}
+
+	results := make([]SyncResult, len(mutations))
+	for i := range mutations {
+		m := &mutations[i]
+		invalid := m.Validate()
+
+		result := &results[i]
+		result.Entity = m.Entity
+		result.ClientID = m.ClientID
+
+		if invalid != nil {
+			result.reject(invalid)
+			continue
+		}
+
+		// Match the database precision, and don't let a client clock that
+		// runs ahead win every future conflict
+		m.UpdatedAt = m.UpdatedAt.Truncate(time.Microsecond)
+		if m.UpdatedAt.After(now) {
+			m.UpdatedAt = now
+		}
+
+		if m.Entity == SyncEntitySession {
+			err = syncSession(ctx, tx, userID, m, result)
+		} else {
+			err = syncError(ctx, tx, userID, m, result)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var cursor int64
+	if err := tx.QueryRow(ctx, `SELECT change_seq FROM users WHERE id = $1`, userID).Scan(&cursor); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, "", err
+	}
+
+	return results, FormatChangeCursor(cursor), nil
+}
+
+// syncSession applies a session mutation
+func syncSession(ctx context.Context, tx pgx.Tx, userID int, m *SyncMutation, result *SyncResult) error {
+	var (
+		id        int
+		updatedAt time.Time
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT id, updated_at FROM sessions
+		WHERE user_id = $1 AND client_id = $2
+		FOR UPDATE
+	`, userID, m.ClientID).Scan(&id, &updatedAt)
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	result.ID = id
+
+	if found {
+		switch {
+		case updatedAt.After(m.UpdatedAt):
+			result.Status = SyncStatusConflict
+		case m.Op == SyncOpUpsert && updatedAt.Equal(m.UpdatedAt):
+			result.Status = SyncStatusUnchanged
+		}
+		if result.Status != "" {
+			result.Session, err = getSyncSession(ctx, tx, id)
+			return err
+		}
+	}
+
+	if m.Op == SyncOpDelete {
+		// Deleting a row that is already gone is a successful replay
+		result.Status = SyncStatusApplied
+		if !found {
+			return nil
+		}
+		return deleteSession(ctx, tx, userID, id)
+	}
+
+	// Don't let an edit made before a delete bring the row back
+	if !found {
+		deleted, err := deletedSince(ctx, tx, userID, ChangeEntitySession, m)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			result.Status = SyncStatusConflict
+			return nil
+		}
+	}
+
+	var opponentID *int
+	opponentName := m.Session.OpponentName
+	if opponentName != "" {
+		opponent, err := findOrCreateOpponent(ctx, tx, userID, opponentName)
+		if err != nil {
+			return err
+		}
+		opponentID = &opponent.ID
+		opponentName = opponent.Name
+	}
+
+	if found {
+		_, err = tx.Exec(ctx, `
+			UPDATE sessions
+			SET name = $2, opponent_id = $3, opponent_name = $4, session_date = $5, updated_at = $6
+			WHERE id = $1
+		`, id, m.Session.Name, opponentID, opponentName, m.Session.SessionDate, m.UpdatedAt)
+	} else {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO sessions (user_id, client_id, name, opponent_id, opponent_name, session_date, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			RETURNING id
+		`, userID, m.ClientID, m.Session.Name, opponentID, opponentName, m.Session.SessionDate, m.UpdatedAt).Scan(&id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordChange(ctx, tx, userID, ChangeEntitySession, id, &m.ClientID, false); err != nil {
+		return err
+	}
+
+	result.Status = SyncStatusApplied
+	result.ID = id
+	result.Session, err = getSyncSession(ctx, tx, id)
+	return err
+}
+
+// syncError applies an error entry mutation
+func syncError(ctx context.Context, tx pgx.Tx, userID int, m *SyncMutation, result *SyncResult) error {
+	var (
+		id        int
+		ownerID   int
+		updatedAt time.Time
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT e.id, e.updated_at, s.user_id
+		FROM errors e
+		JOIN sessions s ON s.id = e.session_id
+		WHERE e.client_id = $1
+		FOR UPDATE OF e
+	`, m.ClientID).Scan(&id, &updatedAt, &ownerID)
+	found := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	// Client IDs are unique across users; don't reveal anything about the other row
+	if found && ownerID != userID {
+		result.reject(apperr.Invalid("client_id", "Client ID cannot be used"))
+		return nil
+	}
+	result.ID = id
+
+	if found {
+		switch {
+		case updatedAt.After(m.UpdatedAt):
+			result.Status = SyncStatusConflict
+		case m.Op == SyncOpUpsert && updatedAt.Equal(m.UpdatedAt):
+			result.Status = SyncStatusUnchanged
+		}
+		if result.Status != "" {
+			result.Entry, err = getSyncErrorEntry(ctx, tx, id)
+			return err
+		}
+	}
+
+	if m.Op == SyncOpDelete {
+		result.Status = SyncStatusApplied
+		if !found {
+			return nil
+		}
+		_, err = changeRows(ctx, tx, userID, ChangeEntityError, true,
+			`DELETE FROM errors WHERE id = $1 RETURNING id, client_id`, id)
+		return err
+	}
+
+	if !found {
+		deleted, err := deletedSince(ctx, tx, userID, ChangeEntityError, m)
+		if err != nil {
+			return err
+		}
+		if deleted {
+			result.Status = SyncStatusConflict
+			return nil
+		}
+	}
+
+	// The session may have been created earlier in the same batch
+	var sessionID int
+	if m.Error.SessionClientID != "" {
+		err = tx.QueryRow(ctx, `
+			SELECT id FROM sessions WHERE user_id = $1 AND client_id = $2
+		`, userID, m.Error.SessionClientID).Scan(&sessionID)
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT id FROM sessions WHERE user_id = $1 AND id = $2
+		`, userID, m.Error.SessionID).Scan(&sessionID)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		field := "error.session_id"
+		if m.Error.SessionClientID != "" {
+			field = "error.session_client_id"
+		}
+		result.reject(apperr.Invalid(field, "Session not found"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	e := m.Error
+	if found {
+		_, err = tx.Exec(ctx, `
+			UPDATE errors
+			SET session_id = $2, count = $3, stroke = NULLIF($4, ''), side = NULLIF($5, ''), outcome = NULLIF($6, ''),
+			    recorded_at = COALESCE($7, recorded_at), updated_at = $8
+			WHERE id = $1
+		`, id, sessionID, e.Count, e.Stroke, e.Side, e.Outcome, e.RecordedAt, m.UpdatedAt)
+	} else {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO errors (session_id, client_id, count, stroke, side, outcome, recorded_at, created_at, updated_at)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), COALESCE($7, $8), $8, $8)
+			RETURNING id
+		`, sessionID, m.ClientID, e.Count, e.Stroke, e.Side, e.Outcome, e.RecordedAt, m.UpdatedAt).Scan(&id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordChange(ctx, tx, userID, ChangeEntityError, id, &m.ClientID, false); err != nil {
+		return err
+	}
+
+	result.Status = SyncStatusApplied
+	result.ID = id
+	result.Entry, err = getSyncErrorEntry(ctx, tx, id)
+	return err
+}
+
+// deletedSince reports whether the row a mutation refers to was deleted on
+// the server after the mutation was made, according to its tombstone
+func deletedSince(ctx context.Context, tx pgx.Tx, userID int, entity string, m *SyncMutation) (bool, error) {
+	var deleted bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM changes
+			WHERE user_id = $1 AND entity = $2 AND client_id = $3 AND deleted AND changed_at >= $4
+		)
+	`, userID, entity, m.ClientID, m.UpdatedAt).Scan(&deleted)
+
+	return deleted, err
+}
+
+// getSyncSession retrieves a session without its error totals
+func getSyncSession(ctx context.Context, q querier, id int) (*Session, error) {
+	var session Session
+
+	query := `
+		SELECT id, user_id, client_id, name, opponent_id, opponent_name, session_date, created_at, updated_at
+		FROM sessions
+		WHERE id = $1
+	`
+
+	err := q.QueryRow(ctx, query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.ClientID,
+		&session.Name,
+		&session.OpponentID,
+		&session.OpponentName,
+		&session.SessionDate,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// getSyncErrorEntry retrieves an error entry
+func getSyncErrorEntry(ctx context.Context, q querier, id int) (*ErrorEntry, error) {
+	var entry ErrorEntry
+
+	query := `
+		SELECT id, session_id, client_id, count, COALESCE(stroke, ''), COALESCE(side, ''), COALESCE(outcome, ''),
+		       recorded_at, created_at, updated_at
+		FROM errors
+		WHERE id = $1
+	`
+
+	err := q.QueryRow(ctx, query, id).Scan(
+		&entry.ID,
+		&entry.SessionID,
+		&entry.ClientID,
+		&entry.Count,
+		&entry.Stroke,
+		&entry.Side,
+		&entry.Outcome,
+		&entry.RecordedAt,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
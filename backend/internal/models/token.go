@@ -0,0 +1,254 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// RefreshTokenTTL is how long a refresh token stays valid if it is not rotated
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// refreshTokenBytes is the amount of randomness in a refresh token
+const refreshTokenBytes = 32
+
+var (
+	// ErrRefreshTokenInvalid is returned for unknown or expired refresh tokens
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated or revoked
+	// refresh token is presented again; its whole family is revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshToken represents a stored refresh token. The plaintext token is only
+// ever known to the client.
+type RefreshToken struct {
+	ID         int
+	UserID     int
+	FamilyID   string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *int
+	CreatedAt  time.Time
+}
+
+// TokenService handles database operations for refresh tokens and revoked access tokens
+type TokenService struct {
+	DB *database.DB
+}
+
+// HashToken returns the hex SHA-256 hash under which a token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// querier is implemented by both the connection pool and transactions
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// insertRefreshToken stores a new refresh token in a family and returns its plaintext and ID
+func insertRefreshToken(ctx context.Context, q querier, userID int, familyID string) (string, int, error) {
+	token, err := RandomToken(refreshTokenBytes)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var id int
+	err = q.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, userID, familyID, HashToken(token), time.Now().Add(RefreshTokenTTL)).Scan(&id)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return token, id, nil
+}
+
+// CreateRefreshToken starts a new token family for a user, e.g. on login, and
+// returns the plaintext refresh token
+func (s *TokenService) CreateRefreshToken(ctx context.Context, userID int) (string, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	familyID, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	token, _, err := insertRefreshToken(ctx, s.DB.Pool, userID, familyID)
+	return token, err
+}
+
+// RotateRefreshToken exchanges a valid refresh token for a new one in the same
+// family and returns the new plaintext token and the owning user ID. Presenting
+// a token that was already rotated or revoked revokes the whole family and
+// returns ErrRefreshTokenReused.
+func (s *TokenService) RotateRefreshToken(ctx context.Context, token string) (string, int, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var current RefreshToken
+	err = tx.QueryRow(ctx, `
+		SELECT id, user_id, family_id, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, HashToken(token)).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.ExpiresAt,
+		&current.RevokedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", 0, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if current.RevokedAt != nil {
+		// Replay of an old token: assume it was stolen and kill the family
+		if _, err := tx.Exec(ctx, `
+			UPDATE refresh_tokens
+			SET revoked_at = NOW()
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`, current.FamilyID); err != nil {
+			return "", 0, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return "", 0, err
+		}
+		return "", 0, ErrRefreshTokenReused
+	}
+
+	if !time.Now().Before(current.ExpiresAt) {
+		return "", 0, ErrRefreshTokenInvalid
+	}
+
+	next, nextID, err := insertRefreshToken(ctx, tx, current.UserID, current.FamilyID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), replaced_by = $2
+		WHERE id = $1
+	`, current.ID, nextID); err != nil {
+		return "", 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", 0, err
+	}
+
+	return next, current.UserID, nil
+}
+
+// RevokeFamily revokes every token in the family of the given refresh token,
+// provided it belongs to the user. It reports whether the token was found.
+func (s *TokenService) RevokeFamily(ctx context.Context, userID int, token string) (bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := s.DB.Pool.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		)
+	`, HashToken(token), userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAllForUser revokes every refresh token of a user and rejects every
+// access token issued so far, logging them out everywhere
+func (s *TokenService) RevokeAllForUser(ctx context.Context, userID int) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.DB.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := revokeAllForUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// revokeAllForUser implements RevokeAllForUser within a transaction
+func revokeAllForUser(ctx context.Context, tx pgx.Tx, userID int) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE users
+		SET tokens_valid_after = NOW()
+		WHERE id = $1
+	`, userID)
+	return err
+}
+
+// RevokeAccessToken denylists an access token by its JWT ID until it expires
+func (s *TokenService) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	// Entries past their expiry are useless, so clean them up as we go
+	if _, err := s.DB.Pool.Exec(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	_, err := s.DB.Pool.Exec(ctx, `
+		INSERT INTO revoked_access_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`, jti, expiresAt)
+
+	return err
+}
+
+// IsAccessTokenRevoked reports whether an access token has been denylisted,
+// or was issued to the user before all their tokens were revoked. Issue times
+// have microsecond precision, like timestamps in Postgres.
+func (s *TokenService) IsAccessTokenRevoked(ctx context.Context, jti string, userID int, issuedAt time.Time) (bool, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	var revoked bool
+	err := s.DB.Pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)
+		    OR EXISTS (SELECT 1 FROM users WHERE id = $2 AND $3 < tokens_valid_after)
+	`, jti, userID, issuedAt).Scan(&revoked)
+
+	return revoked, err
+}
@@ -1,17 +1,58 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgconn"
+	"github.com/jimsyyap/tennis-tracker/backend/internal/database"
+)
+
+// Password policy limits. bcrypt silently ignores anything past 72 bytes,
+// so longer passwords are rejected rather than truncated.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// MaxNameLength is the longest user, session or opponent name, in characters
+const MaxNameLength = 255
+
+// User roles
+const (
+	RolePlayer = "player"
+	RoleCoach  = "coach"
+	RoleAdmin  = "admin"
+)
+
+var (
+	// ErrEmailTaken is returned when the users.email unique constraint is violated
+	ErrEmailTaken = errors.New("email already registered")
+	// ErrInvalidEmail is returned when an email address cannot be parsed
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidName is returned for names that are too long for their column
+	// or have control characters, which could for example break the headers
+	// of emails they appear in
+	ErrInvalidName = errors.New("name must be at most 255 characters without control characters")
+	// ErrWeakPassword is returned when a password does not meet the password policy
+	ErrWeakPassword = errors.New("password must be 8-72 characters and contain at least one letter and one digit")
 )
 
 // User represents a user in the system
 type User struct {
-	ID           int       `json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Never send to client
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	PasswordHash string     `json:"-"` // Never send to client
+	Role         string     `json:"role"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 // UserService handles database operations for users
@@ -20,20 +61,25 @@ type UserService struct {
 }
 
 // GetByID retrieves a user by ID
-func (s *UserService) GetByID(id int) (*User, error) {
+func (s *UserService) GetByID(ctx context.Context, id int) (*User, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	var user User
 	
 	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
+		SELECT id, name, email, password_hash, role, last_login_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
 	
-	err := s.DB.Pool.QueryRow(context.Background(), query, id).Scan(
+	err := s.DB.Pool.QueryRow(ctx, query, id).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&user.LastLoginAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -46,20 +92,25 @@ func (s *UserService) GetByID(id int) (*User, error) {
 }
 
 // GetByEmail retrieves a user by email
-func (s *UserService) GetByEmail(email string) (*User, error) {
+func (s *UserService) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	var user User
 	
 	query := `
-		SELECT id, name, email, password_hash, created_at, updated_at
+		SELECT id, name, email, password_hash, role, last_login_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
 	
-	err := s.DB.Pool.QueryRow(context.Background(), query, email).Scan(
+	err := s.DB.Pool.QueryRow(ctx, query, email).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.PasswordHash,
+		&user.Role,
+		&user.LastLoginAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -71,47 +122,133 @@ func (s *UserService) GetByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// IsAssignableRole reports whether the role can be picked at registration or
+// given by an admin. Admins can only be appointed directly in the database.
+func IsAssignableRole(role string) bool {
+	return role == RolePlayer || role == RoleCoach
+}
+
+// NormalizeEmail trims and lower-cases an email address so lookups and the
+// unique constraint on users.email behave case-insensitively
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks that an email address is a bare, well-formed address
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// ValidateName checks that a user, session or opponent name fits its column
+// and has no control characters such as line breaks
+func ValidateName(name string) error {
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return ErrInvalidName
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return ErrInvalidName
+		}
+	}
+	return nil
+}
+
+// ValidatePassword checks a password against the password policy
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrWeakPassword
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return ErrWeakPassword
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation on the given constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
 // Create inserts a new user into the database
-func (s *UserService) Create(user *User) error {
+func (s *UserService) Create(ctx context.Context, user *User) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO users (name, email, password_hash)
-		VALUES ($1, $2, $3)
+		INSERT INTO users (name, email, password_hash, role)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id, created_at, updated_at
 	`
 	
+	if user.Role == "" {
+		user.Role = RolePlayer
+	}
+	
 	err := s.DB.Pool.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		user.Name,
 		user.Email,
 		user.PasswordHash,
+		user.Role,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	
+	if isUniqueViolation(err, "users_email_key") {
+		return ErrEmailTaken
+	}
+	
 	return err
 }
 
 // Update updates an existing user
-func (s *UserService) Update(user *User) error {
+func (s *UserService) Update(ctx context.Context, user *User) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE users
-		SET name = $2, email = $3, updated_at = NOW()
+		SET name = $2, email = $3, role = $4, updated_at = NOW()
 		WHERE id = $1
 		RETURNING updated_at
 	`
 	
 	err := s.DB.Pool.QueryRow(
-		context.Background(),
+		ctx,
 		query,
 		user.ID,
 		user.Name,
 		user.Email,
+		user.Role,
 	).Scan(&user.UpdatedAt)
 	
+	if isUniqueViolation(err, "users_email_key") {
+		return ErrEmailTaken
+	}
+	
 	return err
 }
 
 // UpdatePassword updates a user's password
-func (s *UserService) UpdatePassword(userID int, passwordHash string) error {
+func (s *UserService) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE users
 		SET password_hash = $2, updated_at = NOW()
@@ -119,7 +256,7 @@ func (s *UserService) UpdatePassword(userID int, passwordHash string) error {
 	`
 	
 	_, err := s.DB.Pool.Exec(
-		context.Background(),
+		ctx,
 		query,
 		userID,
 		passwordHash,
@@ -127,3 +264,18 @@ func (s *UserService) UpdatePassword(userID int, passwordHash string) error {
 	
 	return err
 }
+
+// UpdateLastLogin records the current time as the user's last login
+func (s *UserService) UpdateLastLogin(ctx context.Context, user *User) error {
+	ctx, cancel := s.DB.WithTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET last_login_at = NOW()
+		WHERE id = $1
+		RETURNING last_login_at
+	`
+
+	return s.DB.Pool.QueryRow(ctx, query, user.ID).Scan(&user.LastLoginAt)
+}
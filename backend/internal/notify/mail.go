@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/mailer"
+)
+
+// MailNotifier sends events by email
+type MailNotifier struct {
+	Mailer mailer.Mailer
+	// AppURL is the base URL of the frontend, used in links to the session
+	AppURL string
+}
+
+// Notify emails the event to its recipient. Unknown event types are ignored.
+func (n *MailNotifier) Notify(ctx context.Context, event Event) error {
+	switch event.Type {
+	case EventCommentCreated:
+		return n.Mailer.Send(ctx, n.commentCreated(event))
+	}
+	return nil
+}
+
+// commentCreated builds the email about a new comment
+func (n *MailNotifier) commentCreated(event Event) mailer.Message {
+	name := event.Actor.Name
+	if name == "" {
+		name = event.Actor.Email
+	}
+
+	what := "the session"
+	if event.Comment.ErrorID != nil {
+		what = "an error entry in the session"
+	}
+
+	return mailer.Message{
+		To:      event.Recipient.Email,
+		Subject: fmt.Sprintf("%s commented on %s", name, event.Session.Name),
+		Body: fmt.Sprintf("%s commented on %s %q:\n\n%s\n\nView the session:\n\n%s/sessions/%d\n",
+			name, what, event.Session.Name, event.Comment.Body, n.AppURL, event.Session.ID),
+	}
+}
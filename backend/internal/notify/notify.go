@@ -0,0 +1,45 @@
+// Package notify tells users about activity on their data, such as new
+// comments from a coach
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Event types
+const (
+	// EventCommentCreated is sent when a comment is left on a session the
+	// recipient owns, or in reply to a comment of theirs
+	EventCommentCreated = "comment.created"
+)
+
+// Event describes something a user should hear about
+type Event struct {
+	Type      string
+	Recipient *models.User
+	Actor     *models.User
+	Session   *models.Session
+	Comment   *models.Comment
+}
+
+// Notifier delivers events to their recipient
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Hooks is a Notifier passing every event to each of its notifiers in turn
+type Hooks []Notifier
+
+// Notify calls every notifier, even after one fails, and joins their errors
+func (h Hooks) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range h {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+// Package ratelimit defines the token bucket limits and login lockouts used
+// to throttle clients, and the store that keeps their state
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a token bucket holding Burst tokens that refills one token every
+// Every. Buckets are tracked as the time they will be full again, so a full
+// bucket needs no state at all.
+type Limit struct {
+	Burst int
+	Every time.Duration
+}
+
+// Wait returns how long a request has to wait for a token from a bucket that
+// will be full again after backlog, or zero if a token is available now
+func (l Limit) Wait(backlog time.Duration) time.Duration {
+	if backlog < 0 {
+		backlog = 0
+	}
+	wait := backlog + l.Every - time.Duration(l.Burst)*l.Every
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// LockoutPolicy locks a key out after repeated failures. The After-th failure
+// locks it for Base, and each further one doubles the lockout up to Max.
+// Failures are forgotten once Reset has passed without another one.
+type LockoutPolicy struct {
+	After int
+	Base  time.Duration
+	Max   time.Duration
+	Reset time.Duration
+}
+
+// Duration returns how long a key is locked out after the given number of
+// consecutive failures
+func (p LockoutPolicy) Duration(failures int) time.Duration {
+	if failures < p.After {
+		return 0
+	}
+	lockout := p.Base
+	for i := p.After; i < failures && lockout < p.Max; i++ {
+		lockout *= 2
+	}
+	if lockout > p.Max {
+		return p.Max
+	}
+	return lockout
+}
+
+// Store keeps the token buckets and failed attempt counts
+type Store interface {
+	// Take takes a token from the bucket under key. It returns zero if one
+	// was available, or else how long until one will be.
+	Take(ctx context.Context, key string, limit Limit) (time.Duration, error)
+	// LockedOut returns how much longer key is locked out, or zero if it is not
+	LockedOut(ctx context.Context, key string) (time.Duration, error)
+	// RecordFailure counts a failed attempt for key and returns how long key
+	// is now locked out under the policy, or zero if it is not
+	RecordFailure(ctx context.Context, key string, policy LockoutPolicy) (time.Duration, error)
+	// ClearFailures forgets the failed attempts of key
+	ClearFailures(ctx context.Context, key string) error
+	// Sweep deletes the buckets that have filled up again and the failures
+	// forgotten after reset whose lockout has passed
+	Sweep(ctx context.Context, reset time.Duration) error
+}
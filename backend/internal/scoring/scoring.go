@@ -0,0 +1,360 @@
+// Package scoring derives a tennis score from a sequence of points
+package scoring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Competitor identifies one side of a match, from the session owner's point of view
+type Competitor int
+
+// Competitors
+const (
+	Player Competitor = iota
+	Opponent
+)
+
+// Other returns the other competitor
+func (c Competitor) Other() Competitor {
+	return 1 - c
+}
+
+// String returns the name used for the competitor in the API
+func (c Competitor) String() string {
+	if c == Opponent {
+		return "opponent"
+	}
+	return "player"
+}
+
+// ParseCompetitor parses "player" or "opponent"
+func ParseCompetitor(s string) (Competitor, error) {
+	switch s {
+	case "player":
+		return Player, nil
+	case "opponent":
+		return Opponent, nil
+	}
+	return Player, fmt.Errorf("invalid competitor %q", s)
+}
+
+// MarshalJSON encodes the competitor by name
+func (c Competitor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON decodes a competitor name
+func (c *Competitor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseCompetitor(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Ending describes how a point finished
+type Ending string
+
+// Point endings
+const (
+	EndingWinner        Ending = "winner"
+	EndingUnforcedError Ending = "unforced_error"
+	EndingForcedError   Ending = "forced_error"
+	EndingAce           Ending = "ace"
+	EndingDoubleFault   Ending = "double_fault"
+)
+
+// Valid reports whether the ending is one of the known endings
+func (e Ending) Valid() bool {
+	switch e {
+	case EndingWinner, EndingUnforcedError, EndingForcedError, EndingAce, EndingDoubleFault:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrMatchOver is returned when a point is played after the match has been decided
+	ErrMatchOver = errors.New("match is already over")
+	// ErrInvalidEnding is returned for an unknown point ending
+	ErrInvalidEnding = errors.New("invalid point ending")
+	// ErrAceNotServer is returned when an ace is credited to the receiver
+	ErrAceNotServer = errors.New("an ace must be won by the server")
+	// ErrDoubleFaultServer is returned when a double fault is credited to the server
+	ErrDoubleFaultServer = errors.New("a double fault must be won by the receiver")
+)
+
+// Format describes the rules of a match
+type Format struct {
+	BestOf         int        `json:"best_of"`         // 3 or 5 sets
+	NoAd           bool       `json:"no_ad"`           // Deciding point at deuce instead of advantage
+	MatchTiebreak  bool       `json:"match_tiebreak"`  // Play the final set as a match tiebreak
+	FirstServer    Competitor `json:"first_server"`    // Who serves the first game
+	GamesPerSet    int        `json:"games_per_set"`   // Games needed to win a set, usually 6
+	TiebreakPoints int        `json:"tiebreak_points"` // Points needed to win a set tiebreak, usually 7
+	MatchTBPoints  int        `json:"match_tb_points"` // Points needed to win a match tiebreak, usually 10
+}
+
+// DefaultFormat returns a best-of-3, advantage match with standard tiebreaks
+func DefaultFormat() Format {
+	return Format{
+		BestOf:         3,
+		FirstServer:    Player,
+		GamesPerSet:    6,
+		TiebreakPoints: 7,
+		MatchTBPoints:  10,
+	}
+}
+
+// Validate checks the format and fills in zero values with the defaults
+func (f *Format) Validate() error {
+	def := DefaultFormat()
+	if f.BestOf == 0 {
+		f.BestOf = def.BestOf
+	}
+	if f.GamesPerSet == 0 {
+		f.GamesPerSet = def.GamesPerSet
+	}
+	if f.TiebreakPoints == 0 {
+		f.TiebreakPoints = def.TiebreakPoints
+	}
+	if f.MatchTBPoints == 0 {
+		f.MatchTBPoints = def.MatchTBPoints
+	}
+
+	if f.BestOf != 1 && f.BestOf != 3 && f.BestOf != 5 {
+		return errors.New("best_of must be 1, 3 or 5")
+	}
+	if f.GamesPerSet < 1 || f.TiebreakPoints < 1 || f.MatchTBPoints < 1 {
+		return errors.New("games and tiebreak points must be positive")
+	}
+	if f.FirstServer != Player && f.FirstServer != Opponent {
+		return errors.New("invalid first server")
+	}
+	return nil
+}
+
+// setsToWin returns the number of sets needed to win the match
+func (f Format) setsToWin() int {
+	return f.BestOf/2 + 1
+}
+
+// SetScore is the final score of a completed set
+type SetScore struct {
+	Games    [2]int  `json:"games"`
+	Tiebreak *[2]int `json:"tiebreak,omitempty"` // Tiebreak points, if the set went to one
+}
+
+// Score is the state of a match after a sequence of points
+type Score struct {
+	Sets          []SetScore  `json:"sets"`   // Completed sets
+	Games         [2]int      `json:"games"`  // Games in the current set
+	Points        [2]int      `json:"points"` // Points in the current game or tiebreak
+	Display       [2]string   `json:"display"`
+	Tiebreak      bool        `json:"tiebreak"`
+	MatchTiebreak bool        `json:"match_tiebreak"`
+	Server        Competitor  `json:"server"`
+	Winner        *Competitor `json:"winner,omitempty"`
+}
+
+// Match tracks the score of a match as points are played
+type Match struct {
+	format Format
+
+	sets          []SetScore
+	setsWon       [2]int
+	games         [2]int
+	points        [2]int
+	tiebreak      bool
+	matchTiebreak bool
+	server        Competitor
+	tbFirstServer Competitor
+	winner        *Competitor
+}
+
+// NewMatch starts a match with the given format, which must be valid
+func NewMatch(format Format) *Match {
+	return &Match{
+		format: format,
+		server: format.FirstServer,
+	}
+}
+
+// Format returns the rules of the match
+func (m *Match) Format() Format {
+	return m.format
+}
+
+// Server returns who serves the next point
+func (m *Match) Server() Competitor {
+	return m.server
+}
+
+// Over reports whether the match has been decided
+func (m *Match) Over() bool {
+	return m.winner != nil
+}
+
+// Play records a point won by winner that finished with the given ending
+func (m *Match) Play(winner Competitor, ending Ending) error {
+	if m.Over() {
+		return ErrMatchOver
+	}
+	if !ending.Valid() {
+		return ErrInvalidEnding
+	}
+	if ending == EndingAce && winner != m.server {
+		return ErrAceNotServer
+	}
+	if ending == EndingDoubleFault && winner == m.server {
+		return ErrDoubleFaultServer
+	}
+
+	m.points[winner]++
+	if m.tiebreak {
+		m.playTiebreakPoint(winner)
+	} else {
+		m.playGamePoint(winner)
+	}
+	return nil
+}
+
+// playGamePoint settles a point played in a regular game
+func (m *Match) playGamePoint(winner Competitor) {
+	won, lost := m.points[winner], m.points[winner.Other()]
+
+	// With no-ad scoring the point at deuce decides the game
+	if won < 4 || (!m.format.NoAd && won-lost < 2) {
+		return
+	}
+
+	m.points = [2]int{}
+	m.games[winner]++
+	m.server = m.server.Other()
+
+	games, other := m.games[winner], m.games[winner.Other()]
+	switch {
+	case games >= m.format.GamesPerSet && games-other >= 2:
+		m.winSet(winner, nil)
+	case games == m.format.GamesPerSet && other == m.format.GamesPerSet:
+		m.startTiebreak(false)
+	}
+}
+
+// playTiebreakPoint settles a point played in a set or match tiebreak
+func (m *Match) playTiebreakPoint(winner Competitor) {
+	target := m.format.TiebreakPoints
+	if m.matchTiebreak {
+		target = m.format.MatchTBPoints
+	}
+
+	won, lost := m.points[winner], m.points[winner.Other()]
+	if won >= target && won-lost >= 2 {
+		tb := m.points
+		m.points = [2]int{}
+		m.tiebreak = false
+
+		// The receiver of the first tiebreak point serves the next set
+		m.server = m.tbFirstServer.Other()
+
+		if m.matchTiebreak {
+			m.matchTiebreak = false
+			m.games = [2]int{}
+		}
+		m.games[winner]++
+		m.winSet(winner, &tb)
+		return
+	}
+
+	// The server changes after the first point and then every two points
+	if (won+lost)%2 == 1 {
+		m.server = m.server.Other()
+	}
+}
+
+// startTiebreak switches the current set into a tiebreak
+func (m *Match) startTiebreak(match bool) {
+	m.tiebreak = true
+	m.matchTiebreak = match
+	m.tbFirstServer = m.server
+}
+
+// winSet records the current set for winner and checks whether the match is over
+func (m *Match) winSet(winner Competitor, tiebreak *[2]int) {
+	m.sets = append(m.sets, SetScore{Games: m.games, Tiebreak: tiebreak})
+	m.setsWon[winner]++
+	m.games = [2]int{}
+
+	if m.setsWon[winner] >= m.format.setsToWin() {
+		w := winner
+		m.winner = &w
+		return
+	}
+
+	// The deciding set may be replaced by a match tiebreak
+	last := m.format.setsToWin() - 1
+	if m.format.MatchTiebreak && m.setsWon[Player] == last && m.setsWon[Opponent] == last {
+		m.startTiebreak(true)
+	}
+}
+
+// Score returns the current state of the match
+func (m *Match) Score() Score {
+	score := Score{
+		Sets:          append([]SetScore{}, m.sets...),
+		Games:         m.games,
+		Points:        m.points,
+		Tiebreak:      m.tiebreak,
+		MatchTiebreak: m.matchTiebreak,
+		Server:        m.server,
+		Winner:        m.winner,
+	}
+	score.Display = m.display()
+	return score
+}
+
+// display returns the conventional call of the current game, e.g. "30" and "40"
+func (m *Match) display() [2]string {
+	if m.tiebreak {
+		return [2]string{fmt.Sprint(m.points[Player]), fmt.Sprint(m.points[Opponent])}
+	}
+
+	p, o := m.points[Player], m.points[Opponent]
+	if p >= 3 && o >= 3 && !m.format.NoAd {
+		switch {
+		case p == o:
+			return [2]string{"40", "40"}
+		case p > o:
+			return [2]string{"AD", "40"}
+		default:
+			return [2]string{"40", "AD"}
+		}
+	}
+
+	calls := []string{"0", "15", "30", "40"}
+	return [2]string{calls[min(p, 3)], calls[min(o, 3)]}
+}
+
+// Replay builds a match from a format and the winners and endings of its points in order
+func Replay(format Format, points []Point) (*Match, error) {
+	m := NewMatch(format)
+	for i, p := range points {
+		if err := m.Play(p.Winner, p.Ending); err != nil {
+			return nil, fmt.Errorf("point %d: %w", i+1, err)
+		}
+	}
+	return m, nil
+}
+
+// Point is a single played point
+type Point struct {
+	Winner Competitor `json:"winner"`
+	Ending Ending     `json:"ending"`
+}
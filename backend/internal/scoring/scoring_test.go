@@ -0,0 +1,348 @@
+package scoring
+
+import (
+	"errors"
+	"testing"
+)
+
+// points returns n points won by winner
+func points(winner Competitor, n int) []Competitor {
+	winners := make([]Competitor, n)
+	for i := range winners {
+		winners[i] = winner
+	}
+	return winners
+}
+
+// games returns the points of n love games won by winner
+func games(winner Competitor, n int) []Competitor {
+	return points(winner, 4*n)
+}
+
+// concat joins sequences of point winners
+func concat(seqs ...[]Competitor) []Competitor {
+	var winners []Competitor
+	for _, seq := range seqs {
+		winners = append(winners, seq...)
+	}
+	return winners
+}
+
+// alternate returns n points won alternately, starting with first
+func alternate(first Competitor, n int) []Competitor {
+	winners := make([]Competitor, n)
+	for i := range winners {
+		winners[i] = first
+		first = first.Other()
+	}
+	return winners
+}
+
+// toSixAll returns the points of a set played to 6-6 by games won in turn
+func toSixAll() []Competitor {
+	var winners []Competitor
+	for i := 0; i < 6; i++ {
+		winners = append(winners, games(Player, 1)...)
+		winners = append(winners, games(Opponent, 1)...)
+	}
+	return winners
+}
+
+// play plays winning shots for the winners in order, failing the test on error
+func play(t *testing.T, m *Match, winners []Competitor) {
+	t.Helper()
+	for i, winner := range winners {
+		if err := m.Play(winner, EndingWinner); err != nil {
+			t.Fatalf("point %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestScoring(t *testing.T) {
+	noAd := DefaultFormat()
+	noAd.NoAd = true
+
+	matchTiebreak := DefaultFormat()
+	matchTiebreak.MatchTiebreak = true
+
+	bestOf5 := DefaultFormat()
+	bestOf5.BestOf = 5
+
+	tests := []struct {
+		name    string
+		format  Format
+		winners []Competitor
+		check   func(t *testing.T, m *Match, s Score)
+	}{
+		{
+			name:    "deuce",
+			format:  DefaultFormat(),
+			winners: concat(points(Player, 3), points(Opponent, 3)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Display != [2]string{"40", "40"} {
+					t.Errorf("display = %v, want 40-40", s.Display)
+				}
+			},
+		},
+		{
+			name:    "advantage",
+			format:  DefaultFormat(),
+			winners: concat(points(Player, 3), points(Opponent, 3), points(Player, 1)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Display != [2]string{"AD", "40"} {
+					t.Errorf("display = %v, want AD-40", s.Display)
+				}
+			},
+		},
+		{
+			name:    "advantage back to deuce",
+			format:  DefaultFormat(),
+			winners: concat(points(Player, 3), points(Opponent, 3), points(Player, 1), points(Opponent, 1)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Display != [2]string{"40", "40"} {
+					t.Errorf("display = %v, want 40-40", s.Display)
+				}
+				if s.Games != [2]int{0, 0} {
+					t.Errorf("games = %v, want 0-0", s.Games)
+				}
+			},
+		},
+		{
+			name:    "game from advantage",
+			format:  DefaultFormat(),
+			winners: concat(points(Player, 3), points(Opponent, 3), points(Player, 2)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Games != [2]int{1, 0} {
+					t.Errorf("games = %v, want 1-0", s.Games)
+				}
+				if s.Server != Opponent {
+					t.Errorf("server = %v, want opponent", s.Server)
+				}
+			},
+		},
+		{
+			name:    "no-ad deciding point",
+			format:  noAd,
+			winners: concat(points(Player, 3), points(Opponent, 4)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Games != [2]int{0, 1} {
+					t.Errorf("games = %v, want 0-1", s.Games)
+				}
+				if s.Points != [2]int{0, 0} {
+					t.Errorf("points = %v, want a new game", s.Points)
+				}
+			},
+		},
+		{
+			name:    "six all goes to a tiebreak",
+			format:  DefaultFormat(),
+			winners: toSixAll(),
+			check: func(t *testing.T, m *Match, s Score) {
+				if !s.Tiebreak || s.MatchTiebreak {
+					t.Errorf("tiebreak = %v, match tiebreak = %v, want a set tiebreak", s.Tiebreak, s.MatchTiebreak)
+				}
+				if s.Games != [2]int{6, 6} {
+					t.Errorf("games = %v, want 6-6", s.Games)
+				}
+				if s.Server != Player || m.tbFirstServer != Player {
+					t.Errorf("server = %v, first tiebreak server = %v, want player", s.Server, m.tbFirstServer)
+				}
+			},
+		},
+		{
+			name:    "tiebreak won 7-5",
+			format:  DefaultFormat(),
+			winners: concat(toSixAll(), alternate(Player, 10), points(Player, 2)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if len(s.Sets) != 1 {
+					t.Fatalf("sets = %v, want one set", s.Sets)
+				}
+				set := s.Sets[0]
+				if set.Games != [2]int{7, 6} || set.Tiebreak == nil || *set.Tiebreak != [2]int{7, 5} {
+					t.Errorf("set = %v %v, want 7-6 (7-5)", set.Games, set.Tiebreak)
+				}
+				if s.Tiebreak {
+					t.Error("tiebreak still running after the set")
+				}
+				// The player served first in the tiebreak, so the opponent serves the next set
+				if s.Server != m.tbFirstServer.Other() || s.Server != Opponent {
+					t.Errorf("server = %v, want opponent", s.Server)
+				}
+			},
+		},
+		{
+			name:    "tiebreak needs two clear points",
+			format:  DefaultFormat(),
+			winners: concat(toSixAll(), alternate(Player, 12), points(Player, 1)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if !s.Tiebreak || s.Points != [2]int{7, 6} {
+					t.Errorf("tiebreak = %v, points = %v, want 7-6 in the tiebreak", s.Tiebreak, s.Points)
+				}
+			},
+		},
+		{
+			name:    "match tiebreak at one set all",
+			format:  matchTiebreak,
+			winners: concat(games(Player, 6), games(Opponent, 6)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if !s.Tiebreak || !s.MatchTiebreak {
+					t.Errorf("tiebreak = %v, match tiebreak = %v, want a match tiebreak", s.Tiebreak, s.MatchTiebreak)
+				}
+			},
+		},
+		{
+			name:    "match tiebreak not over at 10-9",
+			format:  matchTiebreak,
+			winners: concat(games(Player, 6), games(Opponent, 6), alternate(Player, 18), points(Player, 1)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if m.Over() {
+					t.Error("match over at 10-9")
+				}
+				if s.Points != [2]int{10, 9} {
+					t.Errorf("points = %v, want 10-9", s.Points)
+				}
+			},
+		},
+		{
+			name:    "match tiebreak won 11-9",
+			format:  matchTiebreak,
+			winners: concat(games(Player, 6), games(Opponent, 6), alternate(Player, 18), points(Player, 2)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Winner == nil || *s.Winner != Player {
+					t.Fatalf("winner = %v, want player", s.Winner)
+				}
+				last := s.Sets[len(s.Sets)-1]
+				if last.Games != [2]int{1, 0} || last.Tiebreak == nil || *last.Tiebreak != [2]int{11, 9} {
+					t.Errorf("last set = %v %v, want 1-0 (11-9)", last.Games, last.Tiebreak)
+				}
+			},
+		},
+		{
+			name:    "match tiebreak won 10-0",
+			format:  matchTiebreak,
+			winners: concat(games(Player, 6), games(Opponent, 6), points(Opponent, 10)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Winner == nil || *s.Winner != Opponent {
+					t.Errorf("winner = %v, want opponent", s.Winner)
+				}
+			},
+		},
+		{
+			name:    "best of 5 not over after two sets",
+			format:  bestOf5,
+			winners: games(Player, 12),
+			check: func(t *testing.T, m *Match, s Score) {
+				if m.Over() || len(s.Sets) != 2 {
+					t.Errorf("over = %v, sets = %d, want a running match after two sets", m.Over(), len(s.Sets))
+				}
+			},
+		},
+		{
+			name:    "best of 5 won three sets to one",
+			format:  bestOf5,
+			winners: concat(games(Player, 12), games(Opponent, 6), games(Player, 6)),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Winner == nil || *s.Winner != Player {
+					t.Fatalf("winner = %v, want player", s.Winner)
+				}
+				if len(s.Sets) != 4 {
+					t.Errorf("sets = %d, want 4", len(s.Sets))
+				}
+			},
+		},
+		{
+			name:    "best of 5 in straight sets",
+			format:  bestOf5,
+			winners: games(Opponent, 18),
+			check: func(t *testing.T, m *Match, s Score) {
+				if s.Winner == nil || *s.Winner != Opponent || len(s.Sets) != 3 {
+					t.Errorf("winner = %v, sets = %d, want the opponent in 3 sets", s.Winner, len(s.Sets))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatch(tt.format)
+			play(t, m, tt.winners)
+			tt.check(t, m, m.Score())
+		})
+	}
+}
+
+func TestTiebreakServerRotation(t *testing.T) {
+	m := NewMatch(DefaultFormat())
+	play(t, m, toSixAll())
+
+	// The server changes after the first point and then every two points
+	want := []Competitor{Opponent, Opponent, Player, Player, Opponent, Opponent, Player, Player}
+	winners := alternate(Player, len(want))
+	for i, server := range want {
+		play(t, m, winners[i:i+1])
+		if got := m.Server(); got != server {
+			t.Errorf("after tiebreak point %d: server = %v, want %v", i+1, got, server)
+		}
+	}
+}
+
+func TestPlayErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		winners []Competitor // Played before the point under test
+		winner  Competitor
+		ending  Ending
+		want    error
+	}{
+		{name: "ace by the server", winner: Player, ending: EndingAce},
+		{name: "ace by the receiver", winner: Opponent, ending: EndingAce, want: ErrAceNotServer},
+		{name: "double fault won by the receiver", winner: Opponent, ending: EndingDoubleFault},
+		{name: "double fault won by the server", winner: Player, ending: EndingDoubleFault, want: ErrDoubleFaultServer},
+		{name: "ace after the serve changed", winners: games(Player, 1), winner: Opponent, ending: EndingAce},
+		{name: "invalid ending", winner: Player, ending: "let", want: ErrInvalidEnding},
+		{name: "match over", winners: games(Player, 12), winner: Player, ending: EndingWinner, want: ErrMatchOver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatch(DefaultFormat())
+			play(t, m, tt.winners)
+			if err := m.Play(tt.winner, tt.ending); !errors.Is(err, tt.want) {
+				t.Errorf("Play() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		want    Format
+		wantErr bool
+	}{
+		{name: "zero value gets the defaults", format: Format{}, want: DefaultFormat()},
+		{
+			name:   "set values are kept",
+			format: Format{BestOf: 5, NoAd: true, FirstServer: Opponent, GamesPerSet: 4, TiebreakPoints: 5, MatchTBPoints: 7},
+			want:   Format{BestOf: 5, NoAd: true, FirstServer: Opponent, GamesPerSet: 4, TiebreakPoints: 5, MatchTBPoints: 7},
+		},
+		{name: "best of 1", format: Format{BestOf: 1}, want: Format{BestOf: 1, GamesPerSet: 6, TiebreakPoints: 7, MatchTBPoints: 10}},
+		{name: "best of 2", format: Format{BestOf: 2}, wantErr: true},
+		{name: "negative games", format: Format{GamesPerSet: -1}, wantErr: true},
+		{name: "invalid first server", format: Format{FirstServer: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.format
+			err := f.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && f != tt.want {
+				t.Errorf("format = %+v, want %+v", f, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package stats computes error trends over a player's sessions
+package stats
+
+import (
+	"sort"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// Rolling average window sizes, in sessions
+const (
+	ShortWindow = 7
+	LongWindow  = 30
+)
+
+// Summary is the aggregate view of a series of sessions
+type Summary struct {
+	SessionCount int                  `json:"session_count"`
+	TotalErrors  int                  `json:"total_errors"`
+	Mean         float64              `json:"mean"`
+	Median       float64              `json:"median"`
+	Rolling7     float64              `json:"rolling_7"`  // Mean of the last 7 sessions
+	Rolling30    float64              `json:"rolling_30"` // Mean of the last 30 sessions
+	Best         *models.SessionTotal `json:"best,omitempty"`
+	Worst        *models.SessionTotal `json:"worst,omitempty"`
+	Streaks      Streaks              `json:"streaks"`
+}
+
+// Streaks counts runs of consecutive sessions with fewer errors than the one before
+type Streaks struct {
+	Current int `json:"current"` // Improvement streak ending at the latest session
+	Longest int `json:"longest"`
+}
+
+// TrendPoint is a session together with the rolling averages up to and including it
+type TrendPoint struct {
+	models.SessionTotal
+	Rolling7  float64 `json:"rolling_7"`
+	Rolling30 float64 `json:"rolling_30"`
+}
+
+// Summarize computes the summary of sessions, which must be ordered oldest first
+func Summarize(sessions []models.SessionTotal) Summary {
+	summary := Summary{SessionCount: len(sessions)}
+	if len(sessions) == 0 {
+		return summary
+	}
+
+	counts := make([]int, len(sessions))
+	for i, s := range sessions {
+		counts[i] = s.ErrorCount
+		summary.TotalErrors += s.ErrorCount
+
+		// Ties go to the most recent session
+		if summary.Best == nil || s.ErrorCount <= summary.Best.ErrorCount {
+			summary.Best = &sessions[i]
+		}
+		if summary.Worst == nil || s.ErrorCount >= summary.Worst.ErrorCount {
+			summary.Worst = &sessions[i]
+		}
+	}
+
+	summary.Mean = float64(summary.TotalErrors) / float64(len(counts))
+	summary.Median = median(counts)
+	summary.Rolling7 = rollingMean(counts, len(counts)-1, ShortWindow)
+	summary.Rolling30 = rollingMean(counts, len(counts)-1, LongWindow)
+	summary.Streaks = streaks(counts)
+
+	return summary
+}
+
+// Trend returns the rolling averages at each session, which must be ordered oldest first
+func Trend(sessions []models.SessionTotal) []TrendPoint {
+	counts := make([]int, len(sessions))
+	for i, s := range sessions {
+		counts[i] = s.ErrorCount
+	}
+
+	trend := make([]TrendPoint, len(sessions))
+	for i, s := range sessions {
+		trend[i] = TrendPoint{
+			SessionTotal: s,
+			Rolling7:     rollingMean(counts, i, ShortWindow),
+			Rolling30:    rollingMean(counts, i, LongWindow),
+		}
+	}
+	return trend
+}
+
+// rollingMean returns the mean of up to window counts ending at index end
+func rollingMean(counts []int, end, window int) float64 {
+	start := end - window + 1
+	if start < 0 {
+		start = 0
+	}
+
+	sum := 0
+	for _, c := range counts[start : end+1] {
+		sum += c
+	}
+	return float64(sum) / float64(end-start+1)
+}
+
+// median returns the median of counts, which must not be empty
+func median(counts []int) float64 {
+	sorted := append([]int(nil), counts...)
+	sort.Ints(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[mid])
+	}
+	return float64(sorted[mid-1]+sorted[mid]) / 2
+}
+
+// streaks finds the current and longest runs of decreasing error counts
+func streaks(counts []int) Streaks {
+	var s Streaks
+	run := 0
+	for i := 1; i < len(counts); i++ {
+		if counts[i] < counts[i-1] {
+			run++
+		} else {
+			run = 0
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+	}
+	s.Current = run
+	return s
+}
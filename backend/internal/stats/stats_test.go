@@ -0,0 +1,222 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/jimsyyap/tennis-tracker/backend/internal/models"
+)
+
+// totals returns sessions with the given error counts, oldest first, numbered from 1
+func totals(counts ...int) []models.SessionTotal {
+	sessions := make([]models.SessionTotal, len(counts))
+	for i, c := range counts {
+		sessions[i] = models.SessionTotal{SessionID: i + 1, ErrorCount: c}
+	}
+	return sessions
+}
+
+// repeat returns n copies of count
+func repeat(count, n int) []int {
+	counts := make([]int, n)
+	for i := range counts {
+		counts[i] = count
+	}
+	return counts
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		counts  []int
+		total   int
+		mean    float64
+		median  float64
+		rolling [2]float64 // 7 and 30 sessions
+		best    int        // Session ID, 0 for none
+		worst   int
+		streaks Streaks
+	}{
+		{
+			name: "no sessions",
+		},
+		{
+			name:    "one session",
+			counts:  []int{4},
+			total:   4,
+			mean:    4,
+			median:  4,
+			rolling: [2]float64{4, 4},
+			best:    1,
+			worst:   1,
+		},
+		{
+			name:    "even number of sessions",
+			counts:  []int{1, 4, 2, 3},
+			total:   10,
+			mean:    2.5,
+			median:  2.5,
+			rolling: [2]float64{2.5, 2.5},
+			best:    1,
+			worst:   2,
+			streaks: Streaks{Current: 0, Longest: 1},
+		},
+		{
+			name:    "ties go to the most recent session",
+			counts:  []int{3, 1, 5, 1, 5},
+			total:   15,
+			mean:    3,
+			median:  3,
+			rolling: [2]float64{3, 3},
+			best:    4,
+			worst:   5,
+			streaks: Streaks{Current: 0, Longest: 1},
+		},
+		{
+			name:    "short window covers the last 7 sessions",
+			counts:  []int{1, 2, 3, 4, 5, 6, 7, 8},
+			total:   36,
+			mean:    4.5,
+			median:  4.5,
+			rolling: [2]float64{5, 4.5},
+			best:    1,
+			worst:   8,
+		},
+		{
+			name:    "long window covers exactly 30 sessions",
+			counts:  append([]int{60}, repeat(0, 29)...),
+			total:   60,
+			mean:    2,
+			median:  0,
+			rolling: [2]float64{0, 2},
+			best:    30,
+			worst:   1,
+			streaks: Streaks{Current: 0, Longest: 1},
+		},
+		{
+			name:    "long window drops the 31st session back",
+			counts:  append([]int{60}, repeat(0, 30)...),
+			total:   60,
+			mean:    60.0 / 31,
+			median:  0,
+			rolling: [2]float64{0, 0},
+			best:    31,
+			worst:   1,
+			streaks: Streaks{Current: 0, Longest: 1},
+		},
+		{
+			name:    "current streak is the longest",
+			counts:  []int{5, 4, 3, 6, 5, 4, 3, 2},
+			total:   32,
+			mean:    4,
+			median:  4,
+			rolling: [2]float64{27.0 / 7, 4},
+			best:    8,
+			worst:   4,
+			streaks: Streaks{Current: 4, Longest: 4},
+		},
+		{
+			name:    "an equal count ends a streak",
+			counts:  []int{5, 4, 3, 3},
+			total:   15,
+			mean:    3.75,
+			median:  3.5,
+			rolling: [2]float64{3.75, 3.75},
+			best:    4,
+			worst:   1,
+			streaks: Streaks{Current: 0, Longest: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Summarize(totals(tt.counts...))
+
+			if s.SessionCount != len(tt.counts) || s.TotalErrors != tt.total {
+				t.Errorf("sessions, total = %d, %d, want %d, %d", s.SessionCount, s.TotalErrors, len(tt.counts), tt.total)
+			}
+			if s.Mean != tt.mean || s.Median != tt.median {
+				t.Errorf("mean, median = %v, %v, want %v, %v", s.Mean, s.Median, tt.mean, tt.median)
+			}
+			if s.Rolling7 != tt.rolling[0] || s.Rolling30 != tt.rolling[1] {
+				t.Errorf("rolling = %v, %v, want %v", s.Rolling7, s.Rolling30, tt.rolling)
+			}
+			if got := sessionID(s.Best); got != tt.best {
+				t.Errorf("best = session %d, want %d", got, tt.best)
+			}
+			if got := sessionID(s.Worst); got != tt.worst {
+				t.Errorf("worst = session %d, want %d", got, tt.worst)
+			}
+			if s.Streaks != tt.streaks {
+				t.Errorf("streaks = %+v, want %+v", s.Streaks, tt.streaks)
+			}
+		})
+	}
+}
+
+// sessionID returns the ID of a session, or 0 for none
+func sessionID(s *models.SessionTotal) int {
+	if s == nil {
+		return 0
+	}
+	return s.SessionID
+}
+
+func TestTrend(t *testing.T) {
+	counts := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	trend := Trend(totals(counts...))
+
+	if len(trend) != len(counts) {
+		t.Fatalf("trend has %d points, want %d", len(trend), len(counts))
+	}
+
+	tests := []struct {
+		index     int
+		rolling7  float64
+		rolling30 float64
+	}{
+		{index: 0, rolling7: 1, rolling30: 1},
+		{index: 1, rolling7: 1.5, rolling30: 1.5},
+		{index: 6, rolling7: 4, rolling30: 4},
+		{index: 7, rolling7: 5, rolling30: 4.5},
+	}
+
+	for _, tt := range tests {
+		p := trend[tt.index]
+		if p.SessionID != tt.index+1 || p.ErrorCount != counts[tt.index] {
+			t.Errorf("point %d is session %d with %d errors, want session %d", tt.index, p.SessionID, p.ErrorCount, tt.index+1)
+		}
+		if p.Rolling7 != tt.rolling7 || p.Rolling30 != tt.rolling30 {
+			t.Errorf("point %d rolling = %v, %v, want %v, %v", tt.index, p.Rolling7, p.Rolling30, tt.rolling7, tt.rolling30)
+		}
+	}
+
+	if trend := Trend(nil); len(trend) != 0 {
+		t.Errorf("trend of no sessions = %+v, want empty", trend)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		counts []int
+		want   float64
+	}{
+		{counts: []int{7}, want: 7},
+		{counts: []int{3, 1}, want: 2},
+		{counts: []int{9, 1, 5}, want: 5},
+		{counts: []int{4, 1, 3, 2}, want: 2.5},
+		{counts: []int{2, 2, 2, 8}, want: 2},
+	}
+
+	for _, tt := range tests {
+		counts := append([]int(nil), tt.counts...)
+		if got := median(counts); got != tt.want {
+			t.Errorf("median(%v) = %v, want %v", tt.counts, got, tt.want)
+		}
+		for i := range counts {
+			if counts[i] != tt.counts[i] {
+				t.Errorf("median(%v) reordered its input to %v", tt.counts, counts)
+				break
+			}
+		}
+	}
+}